@@ -1,11 +1,15 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/ThreeToes/blogvm/internal/assembler"
 	"github.com/ThreeToes/blogvm/internal/machine"
+	"image"
+	"image/png"
 	"os"
+	"os/signal"
 	"path/filepath"
 )
 
@@ -20,6 +24,20 @@ func (i *includeArgs) Set(s string) error {
 	return nil
 }
 
+// savePNG writes img to a PNG file at path
+func savePNG(img image.Image, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(f, img)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 func main() {
 	if len(os.Args) == 1 {
 		fmt.Println("must provide a command:")
@@ -44,6 +62,10 @@ func main() {
 
 		fs := flag.NewFlagSet("run", flag.ExitOnError)
 		filePath := fs.String("file", "", "path to the file to run")
+		diskPath := fs.String("disk", "", "path to a disk image to attach")
+		uartSpec := fs.String("uart", "", "attach a UART to pty, unix:PATH or tcp:ADDRESS, prefix with dial: to connect out")
+		printMap := fs.Bool("map", false, "print the memory map before running")
+		screenPath := fs.String("screen", "", "attach a framebuffer and save it to this PNG when the machine halts")
 		flag.Var(&includes, "include", "add this folder to standard include paths")
 		err = fs.Parse(os.Args[2:])
 		if err != nil {
@@ -57,33 +79,111 @@ func main() {
 		}
 		assembled, err := assembler.AssembleFile(*filePath, includes)
 		if err != nil {
+			var diagnostics assembler.Diagnostics
+			if errors.As(err, &diagnostics) {
+				// Print compiler style so editors can jump to the problems
+				fmt.Fprintln(os.Stderr, diagnostics)
+				return
+			}
 			fmt.Printf("could not assemble program: %v\n", err)
 			return
 		}
 
+		// The console is only put in raw mode once everything else has printed, see below
+		out := &consoleOutput{out: os.Stdout}
+		interrupted := make(chan os.Signal, 1)
+		in := &consoleInput{in: os.Stdin, interrupted: interrupted}
+
 		registers := machine.NewRegisterBank()
 		mem := machine.NewMemory()
-		term := machine.NewTerminal()
+		term := machine.NewTerminal(out, in)
+		defer term.Close()
+		interrupts := machine.NewInterruptController()
+		timer := machine.NewTimer(interrupts)
 		err = mem.Load(assembled)
 		if err != nil {
 			fmt.Printf("could not load assembled program: %v\n", err)
 			return
 		}
-		bus := machine.NewBus(mem, term)
+		dma := machine.NewDMAController(interrupts)
+		devices := []machine.BusDevice{mem, term, interrupts, timer, dma}
+		if *diskPath != "" {
+			disk, err := machine.OpenDisk(*diskPath, interrupts)
+			if err != nil {
+				fmt.Printf("could not open disk image: %v\n", err)
+				return
+			}
+			defer disk.Close()
+			devices = append(devices, disk)
+		}
+		if *uartSpec != "" {
+			uart, err := openUART(*uartSpec, interrupts)
+			if err != nil {
+				fmt.Printf("could not open uart: %v\n", err)
+				return
+			}
+			defer uart.Close()
+			devices = append(devices, uart)
+		}
+		var framebuffer *machine.FramebufferDevice
+		if *screenPath != "" {
+			framebuffer = machine.NewFramebuffer()
+			framebuffer.OnSnapshot(func(img image.Image) error {
+				return savePNG(img, *screenPath)
+			})
+			devices = append(devices, framebuffer)
+		}
+		bus, err := machine.NewBus(devices...)
+		if err != nil {
+			fmt.Printf("could not attach devices: %v\n", err)
+			return
+		}
+		if *printMap {
+			fmt.Println("Memory map")
+			for _, m := range bus.Map() {
+				fmt.Printf("\t%v\n", m)
+			}
+		}
 		cpu := machine.NewCPU(registers, bus)
+		cpu.AttachInterruptController(interrupts)
+		term.AttachInterruptController(interrupts)
 
 		sr, err := registers.GetRegister(machine.SR)
 		if err != nil {
 			fmt.Printf("Could not get the staus register: %v", err)
 			return
 		}
-		fmt.Println("Begin execution")
-		fmt.Println("-------")
+		restore, raw, err := enableRawInput()
+		if err != nil {
+			fmt.Printf("warning: could not put the console in raw mode, keystrokes will arrive a line at a time: %v\n", err)
+			restore = func() {}
+		}
+		defer restore()
+		out.raw = raw
+		signal.Notify(interrupted, os.Interrupt)
+		go func() {
+			<-interrupted
+			restore()
+			os.Exit(1)
+		}()
+
+		fmt.Fprintln(out, "Begin execution")
+		fmt.Fprintln(out, "-------")
 		for sr.Value&machine.STATUS_HALT == 0 {
 			cpu.Tick()
 		}
+		// Stop taking keystrokes and give the console back before printing anything else
+		term.Close()
+		restore()
+		out.raw = false
 		fmt.Println()
 		fmt.Println("-------")
 		fmt.Println("Machine has halted")
+		if framebuffer != nil {
+			err = savePNG(framebuffer.Image(), *screenPath)
+			if err != nil {
+				fmt.Printf("could not save the screen: %v\n", err)
+			}
+		}
 	}
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"sync"
+)
+
+// enableRawInput switches the console to deliver keystrokes as they're typed without echoing them. The
+// returned function puts the console back the way it was, and raw reports whether anything changed. Nothing is
+// changed when stdin isn't a terminal
+func enableRawInput() (restore func(), raw bool, err error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return func() {}, false, nil
+	}
+	saved, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, false, err
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			term.Restore(fd, saved)
+		})
+	}, true, nil
+}
+
+// consoleOutput prints to the console, turning \n into \r\n once raw is set since the console stops doing it for
+// itself in raw mode
+type consoleOutput struct {
+	out io.Writer
+	raw bool
+}
+
+func (c *consoleOutput) Write(p []byte) (int, error) {
+	if !c.raw {
+		return c.out.Write(p)
+	}
+	_, err := c.out.Write(bytes.ReplaceAll(p, []byte("\n"), []byte("\r\n")))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// consoleInput passes keystrokes through, sending an interrupt when Ctrl-C is pressed since the console stops raising
+// SIGINT for itself in raw mode. Outside raw mode Ctrl-C never arrives as a keystroke
+type consoleInput struct {
+	in          io.Reader
+	interrupted chan<- os.Signal
+}
+
+func (c *consoleInput) Read(p []byte) (int, error) {
+	n, err := c.in.Read(p)
+	if bytes.IndexByte(p[:n], 0x03) >= 0 {
+		select {
+		case c.interrupted <- os.Interrupt:
+		default:
+		}
+	}
+	return n, err
+}
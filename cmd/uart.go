@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ThreeToes/blogvm/internal/machine"
+	"strings"
+)
+
+// openUART creates a UART from a -uart flag. The flag is one of:
+// * pty - a new pseudo-terminal
+// * unix:PATH or tcp:ADDRESS - listen for a connection
+// * dial:unix:PATH or dial:tcp:ADDRESS - connect to something already listening, like another machine
+func openUART(spec string, interrupts *machine.InterruptController) (*machine.UARTDevice, error) {
+	if spec == "pty" {
+		uart, path, err := machine.OpenPTYUART(interrupts)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("UART is on %s\n", path)
+		return uart, nil
+	}
+	dial := false
+	if strings.HasPrefix(spec, "dial:") {
+		dial = true
+		spec = strings.TrimPrefix(spec, "dial:")
+	}
+	network, address, ok := strings.Cut(spec, ":")
+	if !ok || (network != "unix" && network != "tcp") {
+		return nil, fmt.Errorf("invalid uart %q, expected pty, unix:PATH or tcp:ADDRESS", spec)
+	}
+	if dial {
+		return machine.DialUART(network, address, interrupts)
+	}
+	uart, addr, err := machine.ListenUART(network, address, interrupts)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("UART is listening on %s\n", addr)
+	return uart, nil
+}
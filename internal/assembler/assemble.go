@@ -9,13 +9,16 @@ import (
 	"strings"
 )
 
+// inputName is the file name given in diagnostics for source that didn't come from a file
+const inputName = "<input>"
+
 func AssembleFile(filePath string, includePaths []string) (*executable.LoadableFile, error) {
 	f, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
-	return Assemble(f, includePaths)
+	return assemble(f, filePath, includePaths)
 }
 
 func AssembleString(input string, includePaths []string) (*executable.LoadableFile, error) {
@@ -23,13 +26,19 @@ func AssembleString(input string, includePaths []string) (*executable.LoadableFi
 	return Assemble(reader, includePaths)
 }
 
+// Assemble assembles a program read from input. When the source has problems the error is the Diagnostics found in
+// both passes
 func Assemble(input io.Reader, includePaths []string) (*executable.LoadableFile, error) {
-	firstPassF, err := firstPass(input, 0x100)
+	return assemble(input, inputName, includePaths)
+}
+
+func assemble(input io.Reader, fileName string, includePaths []string) (*executable.LoadableFile, error) {
+	firstPassF, err := firstPass(input, fileName, 0x100, newPassContext(includePaths))
 	if err != nil {
 		return nil, err
 	}
 
-	imports, err := assembleImports(firstPassF, includePaths)
+	imports, err := assembleImports(firstPassF)
 	if err != nil {
 		return nil, err
 	}
@@ -37,56 +46,39 @@ func Assemble(input io.Reader, includePaths []string) (*executable.LoadableFile,
 	if err != nil {
 		return nil, err
 	}
-	var errs []error
-	for _, v := range firstPassF.symbolTable {
-		switch v.symbolType {
-		case MTDF:
-			errs = append(errs, fmt.Errorf("duplicate symbol %q line %d: %s", v.label, v.relativeLineNumber, v.sourceLine))
-		case INVALID:
-			errs = append(errs, fmt.Errorf("invalid line %d: %q", v.relativeLineNumber, v.sourceLine))
-		}
-	}
-	if err != nil {
-		errString := &strings.Builder{}
-		errString.WriteString("following errors found:")
-		for _, err := range errs {
-			errString.WriteString(fmt.Sprintf("\n\t* %v", err))
-		}
-		return nil, fmt.Errorf(errString.String())
+	firstPassF.layout(0x100)
+	if firstPassF.diagnostics.HasErrors() {
+		return nil, firstPassF.diagnostics
 	}
 	return secondPass(firstPassF)
 }
 
-func assembleImports(records *firstPassFile, includePaths []string) (*firstPassFile, error) {
+// assembleImports gathers the files imported by records and the files they import in turn, which the first pass
+// read as it came to each IMPORT
+func assembleImports(records *firstPassFile) (*firstPassFile, error) {
 	ret := newFirstPassFile()
 	for _, rec := range records.records {
-		if rec.symbolType != IMPORT {
+		if rec.imported == nil {
 			continue
 		}
-		f, err := findFile(rec.sourceLine, includePaths)
-		if err != nil {
-			return nil, err
-		}
-		pass, err := firstPass(f, 0)
-		f.Close()
+		err := ret.merge(rec.imported)
 		if err != nil {
 			return nil, err
 		}
-		err = ret.merge(pass)
+		recs, err := assembleImports(rec.imported)
 		if err != nil {
 			return nil, err
 		}
-		recs, err := assembleImports(pass, includePaths)
+		err = ret.merge(recs)
 		if err != nil {
 			return nil, err
 		}
-		err = ret.merge(recs)
 	}
 	return ret, nil
 }
 
 func findFile(fileName string, searchPath []string) (*os.File, error) {
-	fn := strings.TrimPrefix(fileName, "IMPORT ")
+	fn := fileName
 	if !strings.HasSuffix(fn, ".bs") {
 		fn = fmt.Sprintf("%s.bs", fn)
 	}
@@ -28,18 +28,26 @@ func TestAssembleFile(t *testing.T) {
 				filePath: filepath.Join(basepath, "test_files", "simple_add.bs"),
 			},
 			want: &executable.LoadableFile{
-				BlockCount: 0x01,
-				Flags:      0x00,
+				BlockCount: 0x02,
+				Flags:      executable.FLAG_BLOCK_PROTECTION,
 				Blocks: []*executable.MemoryBlock{
 					{
-						Address:   0x100,
-						BlockSize: 0x06,
+						Address:    0x100,
+						BlockSize:  0x05,
+						Protection: executable.BLOCK_READ | executable.BLOCK_EXECUTE,
 						Words: []uint32{
 							0x03F00005,
 							0x03F10005,
 							0x04010000,
 							0x021F0105,
 							0x00000000,
+						},
+					},
+					{
+						Address:    0x105,
+						BlockSize:  0x01,
+						Protection: executable.BLOCK_READ | executable.BLOCK_WRITE,
+						Words: []uint32{
 							0x00000000,
 						},
 					},
@@ -50,7 +58,7 @@ func TestAssembleFile(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := AssembleFile(tt.args.filePath)
+			got, err := AssembleFile(tt.args.filePath, nil)
 			if !tt.wantErr(t, err, fmt.Sprintf("AssembleFile(%v)", tt.args.filePath)) {
 				return
 			}
@@ -59,38 +67,163 @@ func TestAssembleFile(t *testing.T) {
 	}
 }
 
+// runProgram assembles the file at src, runs it on a machine with RAM and a terminal until it halts, and returns
+// the machine's memory, registers and terminal output. The test fails if the program runs for more than maxTicks
+func runProgram(t *testing.T, src string, includes []string, maxTicks int) (*machine.Memory, *machine.RegisterBank, string) {
+	t.Helper()
+	assembledFile, err := AssembleFile(src, includes)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	mem := machine.NewMemory()
+	term, out := machine.NewBufferedTerminal("")
+	bus, err := machine.NewBus(mem, term)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	registers := machine.NewRegisterBank()
+	cpu := machine.NewCPU(registers, bus)
+	if !assert.NoError(t, mem.Load(assembledFile)) {
+		t.FailNow()
+	}
+	sr, err := registers.GetRegister(machine.SR)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for ticks := 0; sr.Value&machine.STATUS_HALT == 0; ticks++ {
+		if !assert.Less(t, ticks, maxTicks, "program did not halt") {
+			t.FailNow()
+		}
+		if !assert.NoError(t, cpu.Tick()) {
+			t.FailNow()
+		}
+	}
+	return mem, registers, out.String()
+}
+
 func TestRunScenarios(t *testing.T) {
 	_, b, _, _ := runtime.Caller(0)
 	testingFilePath := filepath.Join(filepath.Dir(b), "test_files")
+	includes := []string{filepath.Join(filepath.Dir(b), "..", "..", "lib")}
+	// Stop runaway programs rather than hanging the tests
+	const maxTicks = 10000
 	t.Run("simple add", func(t *testing.T) {
-		addFile := filepath.Join(testingFilePath, "simple_add.bs")
-		assembledFile, err := AssembleFile(addFile)
+		mem, _, _ := runProgram(t, filepath.Join(testingFilePath, "simple_add.bs"), nil, maxTicks)
+		writtenMem, err := mem.Read(0x0105)
 		if !assert.NoError(t, err) {
 			return
 		}
-		mem := machine.NewMemory()
-		bus := machine.NewBus(mem)
-		registers := machine.NewRegisterBank()
-		cpu := machine.NewCPU(registers, bus)
-		err = mem.Load(assembledFile)
+
+		assert.Equal(t, uint32(0x0A), writtenMem)
+	})
+	t.Run("stack frame", func(t *testing.T) {
+		mem, registers, _ := runProgram(t, filepath.Join(testingFilePath, "stack_frame.bs"), nil, maxTicks)
+		sp, err := registers.GetRegister(machine.SP)
 		if !assert.NoError(t, err) {
 			return
 		}
-		sr, err := registers.GetRegister(machine.SR)
+		assert.Equal(t, uint32(0xFFE0), sp.Value)
+		result, err := mem.Read(0x0111)
 		if !assert.NoError(t, err) {
 			return
 		}
-		for sr.Value&machine.STATUS_HALT == 0 {
-			err = cpu.Tick()
-			if !assert.NoError(t, err) {
-				return
-			}
+		assert.Equal(t, uint32(0x06), result)
+	})
+	t.Run("code is read only", func(t *testing.T) {
+		mem, registers, _ := runProgram(t, filepath.Join(testingFilePath, "read_only.bs"), nil, maxTicks)
+		sr, _ := registers.GetRegister(machine.SR)
+		assert.Equal(t, machine.STATUS_MEMORY_ERROR, sr.Value&machine.STATUS_MEMORY_ERROR)
+		code, _ := mem.Read(0x100)
+		assert.Equal(t, uint32(0x03F00001), code)
+		data, _ := mem.Read(0x104)
+		assert.Equal(t, uint32(0x01), data)
+	})
+	t.Run("loose formatting", func(t *testing.T) {
+		mem, _, out := runProgram(t, filepath.Join(testingFilePath, "formatting.bs"), nil, maxTicks)
+		assert.Equal(t, "Hello; world!\n", out)
+		sum, err := mem.Read(0x011D)
+		if !assert.NoError(t, err) {
+			return
 		}
-		writtenMem, err := mem.Read(0x0105)
+		assert.Equal(t, uint32(0x0B), sum)
+	})
+	t.Run("macros", func(t *testing.T) {
+		mem, _, _ := runProgram(t, filepath.Join(testingFilePath, "macros.bs"), includes, maxTicks)
+		// Both counts reach their targets, and POPALL puts R5 back the way PUSHALL found it. PUSHALL and POPALL
+		// expand in place, so RESULT is at 0x126
+		results := make([]uint32, 3)
+		for i := range results {
+			results[i], _ = mem.Read(0x126 + uint32(i))
+		}
+		assert.Equal(t, []uint32{0x10, 0x20, 0x07}, results)
+	})
+	t.Run("constants and expressions", func(t *testing.T) {
+		mem, _, out := runProgram(t, filepath.Join(testingFilePath, "constants.bs"), includes, maxTicks)
+		// COPY FRAMEBUFFER takes two words, so BUFFER is at 0x10B and RESULT at 0x10E
+		results := make([]uint32, 3)
+		for i := range results {
+			results[i], _ = mem.Read(0x10E + uint32(i))
+		}
+		assert.Equal(t, []uint32{3, machine.FRAMEBUFFER, 0x11}, results)
+		assert.Equal(t, "252", out)
+	})
+	t.Run("sections", func(t *testing.T) {
+		sectionsFile := filepath.Join(testingFilePath, "sections.bs")
+		assembledFile, err := AssembleFile(sectionsFile, nil)
 		if !assert.NoError(t, err) {
 			return
 		}
-
-		assert.Equal(t, uint32(0x0A), writtenMem)
+		// The code stays at 0x100 and the data is moved by ORG, while the space for TOTAL after it isn't saved
+		assert.Equal(t, []*executable.MemoryBlock{
+			{
+				Address:    0x100,
+				BlockSize:  0x06,
+				Protection: executable.BLOCK_READ | executable.BLOCK_EXECUTE,
+				Words:      []uint32{0x01F02000, 0x01F12001, 0x04100000, 0x020F2002, 0x020F2005, 0x00000000},
+			},
+			{
+				Address:    0x2000,
+				BlockSize:  0x02,
+				Protection: executable.BLOCK_READ | executable.BLOCK_WRITE,
+				Words:      []uint32{0x05, 0x03},
+			},
+		}, assembledFile.Blocks)
+		mem, _, _ := runProgram(t, sectionsFile, nil, maxTicks)
+		results := make([]uint32, 4)
+		for i := range results {
+			results[i], _ = mem.Read(0x2002 + uint32(i))
+		}
+		assert.Equal(t, []uint32{8, 0, 0, 8}, results)
 	})
 }
+
+func TestExampleOutput(t *testing.T) {
+	_, b, _, _ := runtime.Caller(0)
+	root := filepath.Join(filepath.Dir(b), "..", "..")
+	includes := []string{filepath.Join(root, "lib")}
+	// Stop runaway programs rather than hanging the tests
+	const maxTicks = 100000
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{
+			file:     "ten_factorial.bs",
+			expected: "3628800",
+		},
+		{
+			file:     "print_string.bs",
+			expected: "Hello, world!",
+		},
+		{
+			file:     "copy_to_memory.bs",
+			expected: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			_, _, out := runProgram(t, filepath.Join(root, "examples", tt.file), includes, maxTicks)
+			assert.Equal(t, tt.expected, out)
+		})
+	}
+}
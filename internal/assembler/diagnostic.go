@@ -0,0 +1,100 @@
+package assembler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Severity says whether a diagnostic stops the program assembling
+type Severity uint8
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "unknown"
+}
+
+// Diagnostic is a problem found in a source file. Lines and columns count from 1, and a column of 0 means the
+// problem is with the whole line
+type Diagnostic struct {
+	File     string
+	Line     int
+	Column   int
+	Severity Severity
+	Message  string
+}
+
+// String formats the diagnostic the way compilers do, like "file.bs:12:5: error: message"
+func (d Diagnostic) String() string {
+	if d.Column == 0 {
+		return fmt.Sprintf("%s:%d: %s: %s", d.File, d.Line, d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.File, d.Line, d.Column, d.Severity, d.Message)
+}
+
+// Diagnostics is every problem found while assembling a program. It is returned as the error when any of them are
+// errors
+type Diagnostics []Diagnostic
+
+func (d Diagnostics) Error() string {
+	lines := make([]string, len(d))
+	for i, diag := range d {
+		lines[i] = diag.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// HasErrors reports whether any of the diagnostics are errors rather than warnings
+func (d Diagnostics) HasErrors() bool {
+	for _, diag := range d {
+		if diag.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceError is an error found at a particular column of a line
+type sourceError struct {
+	column  int
+	message string
+}
+
+func (e *sourceError) Error() string {
+	return e.message
+}
+
+func errorAt(column int, format string, args ...interface{}) error {
+	return &sourceError{
+		column:  column,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+// diagnose turns an error from assembling a record into a diagnostic pointing at the record's line. Errors that
+// don't know their column point at the mnemonic
+func diagnose(rec *symbol, err error) Diagnostic {
+	diag := Diagnostic{
+		File:     rec.file,
+		Line:     rec.line,
+		Severity: SeverityError,
+		Message:  err.Error(),
+	}
+	var srcErr *sourceError
+	if errors.As(err, &srcErr) {
+		diag.Column = srcErr.column
+	} else if rec.statement != nil {
+		diag.Column = rec.statement.column
+	}
+	return diag
+}
@@ -0,0 +1,137 @@
+package assembler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDiagnostic_String(t *testing.T) {
+	t.Run("with column", func(t *testing.T) {
+		d := Diagnostic{File: "file.bs", Line: 12, Column: 5, Severity: SeverityError, Message: "bad things"}
+		assert.Equal(t, "file.bs:12:5: error: bad things", d.String())
+	})
+	t.Run("whole line", func(t *testing.T) {
+		d := Diagnostic{File: "file.bs", Line: 3, Severity: SeverityWarning, Message: "odd things"}
+		assert.Equal(t, "file.bs:3: warning: odd things", d.String())
+	})
+}
+
+func TestDiagnostics_Error(t *testing.T) {
+	diags := Diagnostics{
+		{File: "a.bs", Line: 1, Column: 1, Severity: SeverityWarning, Message: "first"},
+		{File: "a.bs", Line: 2, Column: 3, Severity: SeverityError, Message: "second"},
+	}
+	assert.Equal(t, "a.bs:1:1: warning: first\na.bs:2:3: error: second", diags.Error())
+	assert.True(t, diags.HasErrors())
+	assert.False(t, diags[:1].HasErrors())
+}
+
+func TestAssemble_diagnostics(t *testing.T) {
+	_, b, _, _ := runtime.Caller(0)
+	includes := []string{filepath.Join(filepath.Dir(b), "..", "..", "lib")}
+	tests := []struct {
+		name   string
+		source string
+		want   Diagnostics
+	}{
+		{
+			name:   "duplicate symbols",
+			source: "LOOP ADD R0 R1\n  LOOP SUB R0 R1\nHALT",
+			want: Diagnostics{
+				{File: inputName, Line: 2, Column: 3, Severity: SeverityError, Message: `duplicate symbol "LOOP", first defined at <input>:1`},
+			},
+		},
+		{
+			name:   "invalid lines",
+			source: "ADD R0 R1\nFROB R0\nLONELY\nSTRING \"open",
+			want: Diagnostics{
+				{File: inputName, Line: 2, Column: 6, Severity: SeverityError, Message: `unknown instruction "R0" after label "FROB"`},
+				{File: inputName, Line: 3, Column: 1, Severity: SeverityError, Message: `unknown instruction "LONELY"`},
+				{File: inputName, Line: 4, Column: 8, Severity: SeverityError, Message: "unterminated string"},
+			},
+		},
+		{
+			name:   "every second pass error is reported",
+			source: "COPY NOWHERE R0\nHALT\nADD R0, R1, R2\nJMP 0x10000",
+			want: Diagnostics{
+				{File: inputName, Line: 1, Column: 6, Severity: SeverityError, Message: `unrecognised symbol "NOWHERE"`},
+				{File: inputName, Line: 3, Column: 13, Severity: SeverityError, Message: "too many args supplied"},
+			},
+		},
+		{
+			name:   "names starting with a digit",
+			source: "123 HALT\nCOPY 123 R0\n1X EQU 4\nDEFINE 2Y 5",
+			want: Diagnostics{
+				{File: inputName, Line: 1, Column: 1, Severity: SeverityError, Message: `label "123" can't start with a digit`},
+				{File: inputName, Line: 3, Column: 1, Severity: SeverityError, Message: `label "1X" can't start with a digit`},
+				{File: inputName, Line: 4, Column: 8, Severity: SeverityError, Message: `invalid constant name "2Y"`},
+			},
+		},
+		{
+			name:   "constant cycle reported once",
+			source: "COPY A R0\nA EQU B\nDEFINE B A\nWRITE R0 A\nD EQU A + 1\nHALT",
+			want: Diagnostics{
+				{File: inputName, Line: 2, Column: 1, Severity: SeverityError, Message: `constant "A" is defined in terms of itself, through "B"`},
+			},
+		},
+		{
+			name:   "missing import",
+			source: "IMPORT nothing_here\nHALT",
+			want: Diagnostics{
+				{File: inputName, Line: 1, Column: 8, Severity: SeverityError, Message: "could not find file nothing_here.bs on search path"},
+			},
+		},
+		{
+			name:   "extra operands",
+			source: "WORD 1 -1\nADDRESS X R0 R1\nX HALT",
+			want: Diagnostics{
+				{File: inputName, Line: 1, Column: 8, Severity: SeverityError, Message: "too many args supplied"},
+				{File: inputName, Line: 2, Column: 14, Severity: SeverityError, Message: "too many args supplied"},
+			},
+		},
+		{
+			name:   "ORG over other lines",
+			source: "HALT\nHALT\nORG 0x101\nHALT",
+			want: Diagnostics{
+				{File: inputName, Line: 4, Column: 1, Severity: SeverityError, Message: "0x101 is already used by the lines from <input>:1"},
+			},
+		},
+		{
+			name:   "ORG past the end of memory",
+			source: "ORG 0xFFE0\nHALT\nHALT",
+			want: Diagnostics{
+				{File: inputName, Line: 2, Column: 1, Severity: SeverityError, Message: "0xffe0-0xffe1 doesn't fit in memory, which ends at 0xffe0"},
+			},
+		},
+		{
+			name:   "invalid sections",
+			source: "SECTION code\nSECTION\nSECTION data bss\nSECTION bss\nRESERVE 2\nWORD 1",
+			want: Diagnostics{
+				{File: inputName, Line: 1, Column: 9, Severity: SeverityError, Message: `unknown section "code", expected text, data or bss`},
+				{File: inputName, Line: 2, Column: 1, Severity: SeverityError, Message: "SECTION needs a name"},
+				{File: inputName, Line: 3, Column: 14, Severity: SeverityError, Message: "too many args supplied"},
+				{File: inputName, Line: 6, Column: 1, Severity: SeverityError, Message: "only RESERVE can be used in the bss section"},
+			},
+		},
+		{
+			name:   "placement depends on a label",
+			source: "ORG END\nEND HALT\nRESERVE END",
+			want: Diagnostics{
+				{File: inputName, Line: 1, Column: 5, Severity: SeverityError, Message: `label "END" can't be used as a value here`},
+				{File: inputName, Line: 3, Column: 9, Severity: SeverityError, Message: `label "END" can't be used as a value here`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := AssembleString(tt.source, includes)
+			assert.Equal(t, tt.want, err)
+		})
+	}
+	t.Run("importing twice", func(t *testing.T) {
+		_, err := AssembleString("IMPORT term\nIMPORT term\nHALT", includes)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,266 @@
+package assembler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// binaryOperators are the operators that can appear between two values in an expression, from the loosest binding
+// to the tightest
+var binaryOperators = [][]string{
+	{"|"},
+	{"&"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/"},
+}
+
+// isOperator reports whether t is an operator that joins the tokens either side of it into one expression
+func isOperator(t token) bool {
+	if t.tokenType != PUNCT {
+		return false
+	}
+	for _, level := range binaryOperators {
+		for _, op := range level {
+			if t.text == op {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expression evaluates an operand made of literals, symbols, operators and parentheses. Arithmetic wraps around at
+// 32 bits, the same as the CPU
+type expression struct {
+	tokens      []token
+	pos         int
+	symbolTable symbols
+	// labels is set when the expression may refer to the address of a label, rather than only to constants
+	labels bool
+	// usesLabels is set once the expression has referred to a label, directly or through a constant
+	usesLabels bool
+	// visiting holds the constants being evaluated, innermost last, to catch constants defined in terms of themselves
+	visiting []string
+}
+
+// constantCycle is the error for constants defined in terms of themselves. cycle holds the constants involved,
+// starting from the one that refers back to itself
+type constantCycle struct {
+	cycle []string
+}
+
+func (e *constantCycle) Error() string {
+	if len(e.cycle) == 1 {
+		return fmt.Sprintf("constant %q is defined in terms of itself", e.cycle[0])
+	}
+	return fmt.Sprintf("constant %q is defined in terms of itself, through %s", e.cycle[0], strings.Join(quoteAll(e.cycle[1:]), ", "))
+}
+
+// key is the same for every constant in the cycle, whichever one it was found from
+func (e *constantCycle) key() string {
+	names := append([]string(nil), e.cycle...)
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+// involves reports whether name is one of the constants in the cycle
+func (e *constantCycle) involves(name string) bool {
+	return contains(e.cycle, name)
+}
+
+// evaluate works out the value of an operand. It also reports whether the value depends on where labels are, in
+// which case it isn't known until every line has its address
+func evaluate(o operand, symbolTable symbols, labels bool) (uint32, bool, error) {
+	e := &expression{
+		tokens:      o,
+		symbolTable: symbolTable,
+		labels:      labels,
+	}
+	return e.evaluate()
+}
+
+// evaluateConstant works out the value a constant called name is defined as, so a cycle through the constant is
+// found starting from it
+func evaluateConstant(name string, o operand, symbolTable symbols) (uint32, bool, error) {
+	e := &expression{
+		tokens:      o,
+		symbolTable: symbolTable,
+		labels:      true,
+		visiting:    []string{name},
+	}
+	return e.evaluate()
+}
+
+func (e *expression) evaluate() (uint32, bool, error) {
+	if len(e.tokens) == 0 {
+		return 0, false, errorAt(0, "missing value")
+	}
+	value, err := e.binary(0)
+	if err != nil {
+		return 0, false, err
+	}
+	if e.pos < len(e.tokens) {
+		t := e.tokens[e.pos]
+		return 0, false, errorAt(t.column, "unexpected %q", t.text)
+	}
+	return value, e.usesLabels, nil
+}
+
+// peek gives the next token, or nil at the end of the expression
+func (e *expression) peek() *token {
+	if e.pos >= len(e.tokens) {
+		return nil
+	}
+	return &e.tokens[e.pos]
+}
+
+// binary parses operators at the given precedence level and tighter
+func (e *expression) binary(level int) (uint32, error) {
+	if level == len(binaryOperators) {
+		return e.unary()
+	}
+	left, err := e.binary(level + 1)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		t := e.peek()
+		if t == nil || t.tokenType != PUNCT || !contains(binaryOperators[level], t.text) {
+			return left, nil
+		}
+		e.pos++
+		right, err := e.binary(level + 1)
+		if err != nil {
+			return 0, err
+		}
+		switch t.text {
+		case "|":
+			left = left | right
+		case "&":
+			left = left & right
+		case "<<":
+			left = left << right
+		case ">>":
+			left = left >> right
+		case "+":
+			left = left + right
+		case "-":
+			left = left - right
+		case "*":
+			left = left * right
+		case "/":
+			if right == 0 {
+				return 0, errorAt(t.column, "division by zero")
+			}
+			left = left / right
+		}
+	}
+}
+
+func (e *expression) unary() (uint32, error) {
+	t := e.peek()
+	if t != nil && t.tokenType == PUNCT && t.text == "-" {
+		e.pos++
+		value, err := e.unary()
+		return -value, err
+	}
+	if t != nil && t.tokenType == PUNCT && t.text == "+" {
+		e.pos++
+		return e.unary()
+	}
+	return e.primary()
+}
+
+func (e *expression) primary() (uint32, error) {
+	t := e.peek()
+	if t == nil {
+		last := e.tokens[len(e.tokens)-1]
+		return 0, errorAt(last.column, "missing value after %q", last.text)
+	}
+	e.pos++
+	switch {
+	case t.tokenType == PUNCT && t.text == "(":
+		value, err := e.binary(0)
+		if err != nil {
+			return 0, err
+		}
+		if closing := e.peek(); closing == nil || closing.text != ")" {
+			return 0, errorAt(t.column, "missing ')'")
+		}
+		e.pos++
+		return value, nil
+	case t.tokenType == WORD:
+		if sym, ok := e.symbolTable[t.text]; ok {
+			return e.symbolValue(sym, t.column)
+		}
+		value, err := parseLiteral(t.text)
+		if err != nil {
+			return 0, errorAt(t.column, "%v", err)
+		}
+		return value, nil
+	}
+	return 0, errorAt(t.column, "unexpected %q", t.text)
+}
+
+// symbolValue gives the address of a label or the value of a constant
+func (e *expression) symbolValue(sym *symbol, column int) (uint32, error) {
+	switch sym.symbolType {
+	case REL:
+		if !e.labels {
+			return 0, errorAt(column, "label %q can't be used as a value here", sym.label)
+		}
+		e.usesLabels = true
+		return sym.relativeLineNumber, nil
+	case ABS:
+		for i, name := range e.visiting {
+			if name == sym.label {
+				return 0, &constantCycle{cycle: append([]string(nil), e.visiting[i:]...)}
+			}
+		}
+		e.visiting = append(e.visiting, sym.label)
+		defer func() {
+			e.visiting = e.visiting[:len(e.visiting)-1]
+		}()
+		// A constant is worked out from its definition wherever it's used, so it can refer to labels and
+		// constants defined after it
+		inner := &expression{
+			tokens:      constantValue(sym.statement),
+			symbolTable: e.symbolTable,
+			labels:      true,
+			visiting:    e.visiting,
+		}
+		value, usesLabels, err := inner.evaluate()
+		if err != nil {
+			var cycle *constantCycle
+			if errors.As(err, &cycle) {
+				// The cycle is reported where the constants are defined, so it isn't wrapped in every use
+				return 0, err
+			}
+			return 0, errorAt(column, "constant %q: %v", sym.label, err)
+		}
+		e.usesLabels = e.usesLabels || usesLabels
+		return value, nil
+	}
+	return 0, errorAt(column, "unrecognised symbol %q", sym.label)
+}
+
+// quoteAll quotes each of names the way %q would
+func quoteAll(names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	return quoted
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
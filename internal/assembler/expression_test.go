@@ -0,0 +1,88 @@
+package assembler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_evaluate(t *testing.T) {
+	symbolTable := symbols{
+		"START": {
+			symbolType:         REL,
+			label:              "START",
+			relativeLineNumber: 0x100,
+		},
+		"END": {
+			symbolType:         REL,
+			label:              "END",
+			relativeLineNumber: 0x110,
+		},
+		"WIDTH": {
+			symbolType: ABS,
+			label:      "WIDTH",
+			statement:  mustParse("WIDTH EQU 320"),
+		},
+		"HEIGHT": {
+			symbolType: ABS,
+			label:      "HEIGHT",
+			statement:  mustParse("DEFINE HEIGHT 200"),
+		},
+		"LENGTH": {
+			symbolType: ABS,
+			label:      "LENGTH",
+			statement:  mustParse("LENGTH EQU END - START"),
+		},
+		"LOOPY": {
+			symbolType: ABS,
+			label:      "LOOPY",
+			statement:  mustParse("LOOPY EQU LOOPIER + 1"),
+		},
+		"LOOPIER": {
+			symbolType: ABS,
+			label:      "LOOPIER",
+			statement:  mustParse("LOOPIER EQU LOOPY"),
+		},
+	}
+	tests := []struct {
+		name       string
+		expr       string
+		labels     bool
+		want       uint32
+		usesLabels bool
+		wantErr    assert.ErrorAssertionFunc
+	}{
+		{name: "literal", expr: "0x10", want: 0x10, wantErr: assert.NoError},
+		{name: "precedence", expr: "1 + 2 * 3", want: 7, wantErr: assert.NoError},
+		{name: "parentheses", expr: "(1 + 2) * 3", want: 9, wantErr: assert.NoError},
+		{name: "left to right", expr: "10 - 4 - 3", want: 3, wantErr: assert.NoError},
+		{name: "division", expr: "17 / 4", want: 4, wantErr: assert.NoError},
+		{name: "shifts", expr: "1 << 4 | 0x100 >> 8", want: 0x11, wantErr: assert.NoError},
+		{name: "and binds tighter than or", expr: "0xF0 | 0xFF & 0x0F", want: 0xFF, wantErr: assert.NoError},
+		{name: "shift binds looser than addition", expr: "1 << 2 + 1", want: 8, wantErr: assert.NoError},
+		{name: "negative", expr: "-2", want: 0xFFFFFFFE, wantErr: assert.NoError},
+		{name: "constants", expr: "(WIDTH*HEIGHT)", want: 64000, wantErr: assert.NoError},
+		{name: "label", expr: "START+4", labels: true, want: 0x104, usesLabels: true, wantErr: assert.NoError},
+		{name: "label difference", expr: "END-START", labels: true, want: 0x10, usesLabels: true, wantErr: assert.NoError},
+		{name: "constant made from labels", expr: "LENGTH", want: 0x10, usesLabels: true, wantErr: assert.NoError},
+		{name: "labels not allowed", expr: "START", wantErr: assert.Error},
+		{name: "unknown symbol", expr: "NOWHERE + 1", labels: true, wantErr: assert.Error},
+		{name: "division by zero", expr: "1 / 0", wantErr: assert.Error},
+		{name: "missing value", expr: "1 +", wantErr: assert.Error},
+		{name: "missing parenthesis", expr: "(1 + 2", wantErr: assert.Error},
+		{name: "constant defined in terms of itself", expr: "LOOPY", wantErr: assert.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, _, err := tokenize(tt.expr)
+			if !assert.NoError(t, err) {
+				return
+			}
+			got, usesLabels, err := evaluate(tokens, symbolTable, tt.labels)
+			if !tt.wantErr(t, err) || err != nil {
+				return
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.usesLabels, usesLabels)
+		})
+	}
+}
@@ -4,14 +4,38 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"strings"
 )
 
-func firstPass(sourceFile io.Reader, lineNum uint32) (*firstPassFile, error) {
-	ln := lineNum
+// filePass is the first pass over a single file
+type filePass struct {
+	ctx      *passContext
+	fileName string
+	reloc    *firstPassFile
+	address  uint32
+	// section is the section lines are placed in, which every file starts in text
+	section string
+	// defining is set between MACRO and ENDM, while definition is the macro being read. It's nil when the MACRO line
+	// was invalid, in which case the body is skipped
+	defining   bool
+	definition *macro
+	definedAt  int
+	// invocation is the outermost macro being expanded, nil when reading straight from the file
+	invocation *statement
+}
+
+// firstPass reads fileName from sourceFile, working out the address of each line starting from lineNum. Macros are
+// expanded and imported files read as they come up. Problems with the source are collected in the diagnostics of
+// the returned file
+func firstPass(sourceFile io.Reader, fileName string, lineNum uint32, ctx *passContext) (*firstPassFile, error) {
+	p := &filePass{
+		ctx:      ctx,
+		fileName: fileName,
+		reloc:    newFirstPassFile(),
+		address:  lineNum,
+		section:  textSection,
+	}
 	src := bufio.NewReader(sourceFile)
-	reloc := newFirstPassFile()
-	for {
+	for sourceLineNum := 1; ; sourceLineNum++ {
 		line, _, err := src.ReadLine()
 		if err != nil {
 			if err == io.EOF {
@@ -19,121 +43,300 @@ func firstPass(sourceFile io.Reader, lineNum uint32) (*firstPassFile, error) {
 			}
 			return nil, err
 		}
-		rec, err := firstPassLine(ln, string(line))
-		if rec.label != "" {
-			_, ok := reloc.symbolTable[rec.label]
-			if ok {
-				reloc.symbolTable[rec.label] = &symbol{
-					label:              rec.label,
-					symbolType:         MTDF,
-					relativeLineNumber: ln,
-					sourceLine:         string(line),
-				}
-			} else {
-				reloc.symbolTable[rec.label] = rec
+		p.line(sourceLineNum, string(line))
+	}
+	if p.defining && p.definition != nil {
+		p.report(Diagnostic{
+			File:     fileName,
+			Line:     p.definedAt,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("macro %q is missing ENDM", p.definition.name),
+		})
+	}
+	return p.reloc, nil
+}
+
+// line reads a line of source, either from the file or from a macro being expanded
+func (p *filePass) line(lineNum int, line string) {
+	stmt, err := parseLine(line, p.ctx.isKeyword)
+	if p.defining {
+		p.defineLine(lineNum, line, stmt)
+		return
+	}
+	if err == nil {
+		switch {
+		case stmt.mnemonic == "MACRO":
+			p.defining = true
+			p.definedAt = lineNum
+			p.definition, err = p.ctx.newMacro(stmt, p.fileName, lineNum)
+			if err != nil {
+				p.report(p.diagnosticAt(lineNum, stmt, err))
 			}
+			return
+		case stmt.mnemonic == "ENDM":
+			p.report(p.diagnosticAt(lineNum, stmt, errorAt(stmt.column, "ENDM without MACRO")))
+			return
+		case p.ctx.macros[stmt.mnemonic] != nil:
+			p.expand(lineNum, line, stmt, p.ctx.macros[stmt.mnemonic])
+			return
 		}
-		reloc.records = append(reloc.records, rec)
-		if rec.assemblyLink != nil {
-			ln += rec.assemblyLink.calculateSize(string(line))
+		if p.invocation == nil {
+			if label, column, ok := localLabel(stmt); ok {
+				err = errorAt(column, "local label %q can only be used in a macro", label)
+			}
 		}
 	}
-	return reloc, nil
+	var rec *symbol
+	if err != nil {
+		rec = &symbol{
+			symbolType:         INVALID,
+			label:              "",
+			relativeLineNumber: p.address,
+			sourceLine:         line,
+			assemblyLink:       nil,
+		}
+	} else {
+		rec, err = firstPassStatement(p.address, line, stmt)
+	}
+	if d, ok := rec.assemblyLink.(*directive); ok && d.section {
+		p.section, _ = sectionName(stmt)
+	}
+	rec.file = p.fileName
+	rec.line = lineNum
+	rec.section = p.section
+	if err != nil {
+		p.report(diagnose(rec, err))
+	}
+	p.add(rec)
+	if rec.symbolType == IMPORT {
+		p.importFile(rec)
+	}
 }
 
-func firstPassLine(lineNo uint32, line string) (*symbol, error) {
-	cols := strings.Split(line, " ")
-	if len(cols) == 0 {
-		return nil, nil
+// defineLine adds a line to the body of the macro being defined, until its ENDM
+func (p *filePass) defineLine(lineNum int, line string, stmt *statement) {
+	if stmt != nil && stmt.mnemonic == "ENDM" {
+		if p.definition != nil {
+			p.ctx.macros[p.definition.name] = p.definition
+		}
+		p.defining = false
+		p.definition = nil
+		return
 	}
-	op, ok := opcodeTable[cols[0]]
-	if ok {
-		return &symbol{
-			symbolType:         REL,
-			label:              "",
-			relativeLineNumber: lineNo,
-			sourceLine:         line,
-			assemblyLink:       op,
-		}, nil
+	if stmt != nil && stmt.mnemonic == "MACRO" {
+		p.report(p.diagnosticAt(lineNum, stmt, errorAt(stmt.column, "macros can't be defined inside other macros")))
+		return
 	}
-	dir, ok := directiveTable[cols[0]]
-	if ok {
-		return &symbol{
+	if p.definition != nil {
+		p.definition.body = append(p.definition.body, line)
+	}
+}
+
+// expand reads the lines a macro expands to in place of the line that uses it
+func (p *filePass) expand(lineNum int, line string, stmt *statement, m *macro) {
+	if p.ctx.depth >= macroDepthLimit {
+		p.report(p.diagnosticAt(lineNum, stmt, errorAt(stmt.column, "macro %q is nested too deeply, does it use itself?", m.name)))
+		return
+	}
+	p.ctx.expansions++
+	lines, err := m.expand(stmt.operands, p.ctx.expansions)
+	if err != nil {
+		p.report(p.diagnosticAt(lineNum, stmt, errorAt(stmt.column, "%v", err)))
+		return
+	}
+	if stmt.label != "" {
+		// The label goes on the first line the macro expands to, as an empty record that can still be referred to
+		p.add(&symbol{
 			symbolType:         REL,
-			label:              "",
-			relativeLineNumber: lineNo,
+			label:              stmt.label,
+			relativeLineNumber: p.address,
 			sourceLine:         line,
-			assemblyLink:       dir,
-		}, nil
+			file:               p.fileName,
+			line:               lineNum,
+			section:            p.section,
+			statement:          stmt,
+			assemblyLink:       nil,
+		})
+	}
+	if p.invocation == nil {
+		p.invocation = stmt
+		defer func() {
+			p.invocation = nil
+		}()
+	}
+	p.ctx.depth++
+	defer func() {
+		p.ctx.depth--
+	}()
+	for _, l := range lines {
+		p.line(lineNum, l)
+	}
+}
+
+// importFile reads the file an IMPORT asks for, unless it has already been read
+func (p *filePass) importFile(rec *symbol) {
+	f, err := findFile(stringText(rec.statement), p.ctx.includePaths)
+	if err != nil {
+		p.report(diagnose(rec, errorAt(rec.statement.operands[0].column(), "%v", err)))
+		return
+	}
+	defer f.Close()
+	if p.ctx.imported[f.Name()] {
+		return
+	}
+	p.ctx.imported[f.Name()] = true
+	pass, err := firstPass(f, f.Name(), 0, p.ctx)
+	if err != nil {
+		p.report(diagnose(rec, err))
+		return
 	}
-	if cols[0] == "IMPORT" {
-		if len(cols) < 2 {
-			return nil, fmt.Errorf("import statement is not valid")
+	rec.imported = pass
+}
+
+// add records a line, putting its label in the symbol table
+func (p *filePass) add(rec *symbol) {
+	if rec.label != "" {
+		original, ok := p.reloc.symbolTable[rec.label]
+		if ok {
+			p.report(duplicateSymbol(rec, original))
+			p.reloc.symbolTable[rec.label] = &symbol{
+				label:              rec.label,
+				symbolType:         MTDF,
+				relativeLineNumber: p.address,
+				sourceLine:         rec.sourceLine,
+				file:               rec.file,
+				line:               rec.line,
+			}
+		} else {
+			p.reloc.symbolTable[rec.label] = rec
 		}
-		return &symbol{
-			symbolType:         IMPORT,
-			label:              "",
-			relativeLineNumber: lineNo,
-			sourceLine:         line,
-			assemblyLink:       nil,
-		}, nil
 	}
-	if line[0] == ';' {
+	p.reloc.records = append(p.reloc.records, rec)
+	p.address += rec.size(p.reloc.symbolTable)
+}
+
+// diagnosticAt reports err on a line that didn't make a record
+func (p *filePass) diagnosticAt(lineNum int, stmt *statement, err error) Diagnostic {
+	return diagnose(&symbol{
+		file:      p.fileName,
+		line:      lineNum,
+		statement: stmt,
+	}, err)
+}
+
+// report adds a diagnostic. Problems inside a macro are reported against the line that used it, since that's the
+// line in the file
+func (p *filePass) report(d Diagnostic) {
+	if p.invocation != nil {
+		d.Column = p.invocation.column
+		d.Message = fmt.Sprintf("in macro %q: %s", p.invocation.mnemonic, d.Message)
+	}
+	p.reloc.diagnostics = append(p.reloc.diagnostics, d)
+}
+
+// firstPassStatement makes the record for a parsed line of source
+func firstPassStatement(lineNo uint32, line string, stmt *statement) (*symbol, error) {
+	if stmt.mnemonic == "" {
+		// A label on its own is only allowed in front of a comment, otherwise it's most likely a mistyped mnemonic
+		if stmt.label != "" && !stmt.comment {
+			return &symbol{
+				symbolType:         INVALID,
+				label:              "",
+				relativeLineNumber: lineNo,
+				sourceLine:         line,
+				assemblyLink:       nil,
+			}, errorAt(stmt.labelColumn, "unknown instruction %q", stmt.label)
+		}
 		return &symbol{
 			symbolType:         COMMENT,
-			label:              "",
+			label:              stmt.label,
 			relativeLineNumber: lineNo,
 			sourceLine:         line,
+			statement:          stmt,
 			assemblyLink:       nil,
 		}, nil
 	}
-
-	if len(cols) == 1 {
+	if op, ok := opcodeTable[stmt.mnemonic]; ok {
 		return &symbol{
-			symbolType:         INVALID,
-			label:              "",
+			symbolType:         REL,
+			label:              stmt.label,
 			relativeLineNumber: lineNo,
 			sourceLine:         line,
-			assemblyLink:       nil,
+			statement:          stmt,
+			assemblyLink:       op,
 		}, nil
 	}
-
-	label := cols[0]
-	op, ok = opcodeTable[cols[1]]
-	if ok {
+	if dir, ok := directiveTable[stmt.mnemonic]; ok && dir.constant {
+		name, _, err := constantName(stmt)
+		if err != nil {
+			return &symbol{
+				symbolType:         INVALID,
+				label:              "",
+				relativeLineNumber: lineNo,
+				sourceLine:         line,
+				assemblyLink:       nil,
+			}, err
+		}
 		return &symbol{
-			symbolType:         REL,
-			label:              label,
+			symbolType:         ABS,
+			label:              name,
 			relativeLineNumber: lineNo,
 			sourceLine:         line,
-			assemblyLink:       op,
+			statement:          stmt,
+			assemblyLink:       dir,
 		}, nil
 	}
-	dir, ok = directiveTable[cols[1]]
-	if ok {
+	if dir, ok := directiveTable[stmt.mnemonic]; ok && dir.section {
+		if _, err := sectionName(stmt); err != nil {
+			return &symbol{
+				symbolType:         INVALID,
+				label:              "",
+				relativeLineNumber: lineNo,
+				sourceLine:         line,
+				assemblyLink:       nil,
+			}, err
+		}
+	}
+	if dir, ok := directiveTable[stmt.mnemonic]; ok {
 		return &symbol{
 			symbolType:         REL,
-			label:              label,
+			label:              stmt.label,
 			relativeLineNumber: lineNo,
 			sourceLine:         line,
+			statement:          stmt,
 			assemblyLink:       dir,
 		}, nil
 	}
-	if cols[1][0] == ';' {
+	if stmt.mnemonic == "IMPORT" && stmt.label == "" {
+		if stmt.text == "" {
+			return &symbol{
+				symbolType:         INVALID,
+				label:              "",
+				relativeLineNumber: lineNo,
+				sourceLine:         line,
+				assemblyLink:       nil,
+			}, errorAt(stmt.column, "import statement is not valid")
+		}
 		return &symbol{
-			symbolType:         COMMENT,
-			label:              label,
+			symbolType:         IMPORT,
+			label:              "",
 			relativeLineNumber: lineNo,
 			sourceLine:         line,
+			statement:          stmt,
 			assemblyLink:       nil,
 		}, nil
 	}
 
+	err := errorAt(stmt.column, "unknown instruction %q", stmt.mnemonic)
+	if stmt.label != "" {
+		// A mistyped mnemonic gets taken for a label, so name both words
+		err = errorAt(stmt.column, "unknown instruction %q after label %q", stmt.mnemonic, stmt.label)
+	}
 	return &symbol{
 		symbolType:         INVALID,
 		label:              "",
 		relativeLineNumber: lineNo,
 		sourceLine:         line,
 		assemblyLink:       nil,
-	}, nil
+	}, err
 }
@@ -1,5 +1,10 @@
 package assembler
 
+import (
+	"fmt"
+	"sort"
+)
+
 type symbolType uint8
 
 const (
@@ -16,24 +21,43 @@ type symbol struct {
 	label              string
 	relativeLineNumber uint32
 	sourceLine         string
-	assemblyLink       assemblable
+	// file and line are where sourceLine came from, with lines counted from 1
+	file string
+	line int
+	// section is the section the record is placed in, see sectionOrder
+	section string
+	// statement is sourceLine after parsing, nil if the line couldn't be parsed
+	statement    *statement
+	assemblyLink assemblable
+	// imported is the first pass of the file an IMPORT brings in, nil if the file was already imported
+	imported *firstPassFile
 }
 
 func (s *symbol) assemble(symbolTable symbols) ([]uint32, error) {
 	if s.assemblyLink != nil {
-		return s.assemblyLink.assemble(s.sourceLine, symbolTable)
+		return s.assemblyLink.assemble(s.statement, symbolTable)
 	}
 	return nil, nil
 }
 
+// size gives the number of words the symbol's line assembles to
+func (s *symbol) size(symbolTable symbols) uint32 {
+	if s.assemblyLink == nil {
+		return 0
+	}
+	return s.assemblyLink.calculateSize(s.statement, symbolTable)
+}
+
 type symbols map[string]*symbol
 
 type firstPassFile struct {
 	symbolTable symbols
 	records     []*symbol
+	diagnostics Diagnostics
 }
 
 func (r *firstPassFile) merge(other *firstPassFile) error {
+	r.diagnostics = append(r.diagnostics, other.diagnostics...)
 	newSymbolTable := symbols{}
 	originalLength := len(r.records)
 	newLength := originalLength + len(other.records)
@@ -50,7 +74,7 @@ func (r *firstPassFile) merge(other *firstPassFile) error {
 			newSymbolTable[s.label] = s
 		}
 		if idx == originalLength-1 {
-			lineOffset = s.relativeLineNumber + s.assemblyLink.calculateSize(s.sourceLine)
+			lineOffset = s.relativeLineNumber + s.size(r.symbolTable)
 		}
 	}
 	offset := uint32(originalLength)
@@ -61,11 +85,17 @@ func (r *firstPassFile) merge(other *firstPassFile) error {
 			label:              rec.label,
 			relativeLineNumber: newLineNum,
 			sourceLine:         rec.sourceLine,
+			file:               rec.file,
+			line:               rec.line,
+			section:            rec.section,
+			statement:          rec.statement,
 			assemblyLink:       rec.assemblyLink,
+			imported:           rec.imported,
 		}
 		newRecordList[offset+uint32(idx)] = recCopy
 		if rec.label != "" {
 			if retrieved, ok := newSymbolTable[rec.label]; ok {
+				r.diagnostics = append(r.diagnostics, duplicateSymbol(recCopy, retrieved))
 				retrieved.symbolType = MTDF
 			} else {
 				newSymbolTable[rec.label] = recCopy
@@ -78,6 +108,91 @@ func (r *firstPassFile) merge(other *firstPassFile) error {
 	return nil
 }
 
+// memoryEnd is the first address after RAM, where the devices start. Programs have to fit below it
+const memoryEnd = 0xFFE1
+
+// region is a run of addresses filled by consecutive records
+type region struct {
+	start, end uint32
+	// rec is the record at the start of the region
+	rec *symbol
+}
+
+// layout gives every record its address. The records are grouped into their sections, which are placed one after
+// the other counting up from start unless an ORG moves them. Sizes can depend on constants from any file, so the
+// addresses worked out while reading each file only hold once every file is merged and laid out again
+func (r *firstPassFile) layout(start uint32) {
+	grouped := make([][]*symbol, len(sectionOrder))
+	for _, rec := range r.records {
+		idx := sectionOrder[rec.section]
+		grouped[idx] = append(grouped[idx], rec)
+	}
+	r.records = r.records[:0]
+	var regions []*region
+	address := start
+	for _, section := range grouped {
+		for _, rec := range section {
+			if d, ok := rec.assemblyLink.(*directive); ok && d.origin {
+				// A bad ORG is reported when it's assembled
+				if origin, err := placementValue(rec.statement, r.symbolTable); err == nil {
+					address = origin
+				}
+			}
+			rec.relativeLineNumber = address
+			r.records = append(r.records, rec)
+			size := rec.size(r.symbolTable)
+			if size == 0 {
+				continue
+			}
+			if d, ok := rec.assemblyLink.(*directive); rec.section == bssSection && (!ok || !d.uninitialised) {
+				r.diagnostics = append(r.diagnostics, diagnose(rec, fmt.Errorf("only RESERVE can be used in the bss section")))
+			}
+			if len(regions) > 0 && regions[len(regions)-1].end == address {
+				regions[len(regions)-1].end += size
+			} else {
+				regions = append(regions, &region{start: address, end: address + size, rec: rec})
+			}
+			address += size
+		}
+	}
+	r.checkRegions(regions)
+}
+
+// checkRegions reports regions that run past the end of memory or overlap each other, which happens when an ORG
+// places lines where others already are
+func (r *firstPassFile) checkRegions(regions []*region) {
+	sort.SliceStable(regions, func(i, j int) bool {
+		return regions[i].start < regions[j].start
+	})
+	for i, reg := range regions {
+		if reg.end > memoryEnd || reg.end < reg.start {
+			r.diagnostics = append(r.diagnostics, diagnose(reg.rec, fmt.Errorf("%#x-%#x doesn't fit in memory, which ends at %#x", reg.start, reg.end-1, memoryEnd-1)))
+		}
+		if i > 0 && reg.start < regions[i-1].end {
+			prev := regions[i-1]
+			r.diagnostics = append(r.diagnostics, diagnose(reg.rec, fmt.Errorf("%#x is already used by the lines from %s:%d", reg.start, prev.rec.file, prev.rec.line)))
+		}
+	}
+}
+
+// duplicateSymbol reports rec defining a label that was already defined by original
+func duplicateSymbol(rec, original *symbol) Diagnostic {
+	column := 0
+	if rec.statement != nil {
+		column = rec.statement.labelColumn
+		if d, ok := rec.assemblyLink.(*directive); ok && d.constant {
+			_, column, _ = constantName(rec.statement)
+		}
+	}
+	return Diagnostic{
+		File:     rec.file,
+		Line:     rec.line,
+		Column:   column,
+		Severity: SeverityError,
+		Message:  fmt.Sprintf("duplicate symbol %q, first defined at %s:%d", rec.label, original.file, original.line),
+	}
+}
+
 func newFirstPassFile() *firstPassFile {
 	return &firstPassFile{
 		symbolTable: symbols{},
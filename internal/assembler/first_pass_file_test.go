@@ -21,6 +21,7 @@ func Test_relocatableFile_merge(t *testing.T) {
 			label:              "TEST",
 			relativeLineNumber: 0,
 			sourceLine:         "TEST COPY 0x01 R0",
+			statement:          mustParse("TEST COPY 0x01 R0"),
 			assemblyLink:       opcodeTable["COPY"],
 		}
 		r1 := &firstPassFile{
@@ -44,6 +45,7 @@ func Test_relocatableFile_merge(t *testing.T) {
 			label:              "TEST",
 			relativeLineNumber: 0,
 			sourceLine:         "TEST COPY 0x01 R0",
+			statement:          mustParse("TEST COPY 0x01 R0"),
 			assemblyLink:       opcodeTable["COPY"],
 		}
 		r2 := &firstPassFile{
@@ -67,6 +69,7 @@ func Test_relocatableFile_merge(t *testing.T) {
 			label:              "",
 			relativeLineNumber: 0,
 			sourceLine:         "HALT",
+			statement:          mustParse("HALT"),
 			assemblyLink:       opcodeTable["HALT"],
 		}
 		s2 := &symbol{
@@ -74,6 +77,7 @@ func Test_relocatableFile_merge(t *testing.T) {
 			label:              "GREETING",
 			relativeLineNumber: 1,
 			sourceLine:         "GREETING STRING hello",
+			statement:          mustParse("GREETING STRING hello"),
 			assemblyLink:       directiveTable["STRING"],
 		}
 		s3 := &symbol{
@@ -81,6 +85,7 @@ func Test_relocatableFile_merge(t *testing.T) {
 			label:              "MAGICNUMBER",
 			relativeLineNumber: 0,
 			sourceLine:         "MAGICNUMBER WORD 0xDEADBEEF",
+			statement:          mustParse("MAGICNUMBER WORD 0xDEADBEEF"),
 			assemblyLink:       directiveTable["WORD"],
 		}
 
@@ -89,6 +94,7 @@ func Test_relocatableFile_merge(t *testing.T) {
 			label:              "MAGICNUMBER",
 			relativeLineNumber: 7,
 			sourceLine:         "MAGICNUMBER WORD 0xDEADBEEF",
+			statement:          mustParse("MAGICNUMBER WORD 0xDEADBEEF"),
 			assemblyLink:       directiveTable["WORD"],
 		}
 
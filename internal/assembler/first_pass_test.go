@@ -8,10 +8,10 @@ import (
 	"testing"
 )
 
-func Test_firstPassLine(t *testing.T) {
+func Test_firstPassStatement(t *testing.T) {
 	t.Run("test op code record no label", func(t *testing.T) {
 		const line = "ADD 0x10 R1"
-		rec, err := firstPassLine(10, line)
+		rec, err := firstPassStatement(10, line, mustParse(line))
 		if !assert.NoError(t, err) {
 			return
 		}
@@ -22,7 +22,7 @@ func Test_firstPassLine(t *testing.T) {
 	})
 	t.Run("test op code record with label", func(t *testing.T) {
 		const line = "YEEHAW ADD 0x10 R1"
-		rec, err := firstPassLine(10, line)
+		rec, err := firstPassStatement(10, line, mustParse(line))
 		if !assert.NoError(t, err) {
 			return
 		}
@@ -33,7 +33,7 @@ func Test_firstPassLine(t *testing.T) {
 	})
 	t.Run("test directive no label", func(t *testing.T) {
 		const line = "WORD 0x7FFF"
-		rec, err := firstPassLine(10, line)
+		rec, err := firstPassStatement(10, line, mustParse(line))
 		if !assert.NoError(t, err) {
 			return
 		}
@@ -44,7 +44,7 @@ func Test_firstPassLine(t *testing.T) {
 	})
 	t.Run("test directive with label", func(t *testing.T) {
 		const line = "IMPORTANTNUMBER WORD 0x7FFF"
-		rec, err := firstPassLine(10, line)
+		rec, err := firstPassStatement(10, line, mustParse(line))
 		if !assert.NoError(t, err) {
 			return
 		}
@@ -55,7 +55,7 @@ func Test_firstPassLine(t *testing.T) {
 	})
 	t.Run("test comment without label", func(t *testing.T) {
 		const line = ";IMPORTANTNUMBER WORD 0x7FFF"
-		rec, err := firstPassLine(10, line)
+		rec, err := firstPassStatement(10, line, mustParse(line))
 		if !assert.NoError(t, err) {
 			return
 		}
@@ -66,7 +66,7 @@ func Test_firstPassLine(t *testing.T) {
 	})
 	t.Run("test comment with label", func(t *testing.T) {
 		const line = "SOMECOMMENT ;this is a comment"
-		rec, err := firstPassLine(10, line)
+		rec, err := firstPassStatement(10, line, mustParse(line))
 		if !assert.NoError(t, err) {
 			return
 		}
@@ -76,6 +76,34 @@ func Test_firstPassLine(t *testing.T) {
 		assert.Equal(t, uint32(10), rec.relativeLineNumber)
 		assert.Equal(t, line, rec.sourceLine)
 	})
+	t.Run("test EQU constant", func(t *testing.T) {
+		const line = "WIDTH EQU 320"
+		rec, err := firstPassStatement(10, line, mustParse(line))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "WIDTH", rec.label)
+		assert.Equal(t, ABS, rec.symbolType)
+		assert.Equal(t, directiveTable["EQU"], rec.assemblyLink)
+		assert.Equal(t, uint32(0), rec.size(symbols{}))
+	})
+	t.Run("test DEFINE constant", func(t *testing.T) {
+		const line = "DEFINE HEIGHT 200 ; rows"
+		rec, err := firstPassStatement(10, line, mustParse(line))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "HEIGHT", rec.label)
+		assert.Equal(t, ABS, rec.symbolType)
+		assert.Equal(t, directiveTable["DEFINE"], rec.assemblyLink)
+	})
+	t.Run("test invalid constants", func(t *testing.T) {
+		for _, line := range []string{"EQU 5", "DEFINE 5", "X DEFINE Y 5", "DEFINE R0 5", "DEFINE HALT 5"} {
+			rec, err := firstPassStatement(10, line, mustParse(line))
+			assert.Error(t, err, line)
+			assert.Equal(t, INVALID, rec.symbolType, line)
+		}
+	})
 }
 
 func Test_firstPass(t *testing.T) {
@@ -101,6 +129,10 @@ func Test_firstPass(t *testing.T) {
 						label:              "",
 						relativeLineNumber: 0x100,
 						sourceLine:         "ADD R0 R1",
+						file:               "test.bs",
+						line:               1,
+						section:            textSection,
+						statement:          mustParse("ADD R0 R1"),
 						assemblyLink:       opcodeTable["ADD"],
 					},
 				},
@@ -120,6 +152,10 @@ READ DEADBEEF R0`),
 						label:              "DEADBEEF",
 						relativeLineNumber: 0x100,
 						sourceLine:         "DEADBEEF WORD 0xDEADBEEF",
+						file:               "test.bs",
+						line:               1,
+						section:            textSection,
+						statement:          mustParse("DEADBEEF WORD 0xDEADBEEF"),
 						assemblyLink:       directiveTable["WORD"],
 					},
 				},
@@ -129,6 +165,10 @@ READ DEADBEEF R0`),
 						label:              "DEADBEEF",
 						relativeLineNumber: 0x100,
 						sourceLine:         "DEADBEEF WORD 0xDEADBEEF",
+						file:               "test.bs",
+						line:               1,
+						section:            textSection,
+						statement:          mustParse("DEADBEEF WORD 0xDEADBEEF"),
 						assemblyLink:       directiveTable["WORD"],
 					},
 					{
@@ -136,16 +176,67 @@ READ DEADBEEF R0`),
 						label:              "",
 						relativeLineNumber: 0x101,
 						sourceLine:         "READ DEADBEEF R0",
+						file:               "test.bs",
+						line:               2,
+						section:            textSection,
+						statement:          mustParse("READ DEADBEEF R0"),
 						assemblyLink:       opcodeTable["READ"],
 					},
 				},
 			},
 			wantErr: assert.NoError,
 		},
+		{
+			name: "long immediate takes two words",
+			args: args{
+				sourceFile: strings.NewReader(`COPY 0x12345 R0
+NEXT HALT`),
+			},
+			passFile: &firstPassFile{
+				symbolTable: symbols{
+					"NEXT": {
+						symbolType:         REL,
+						label:              "NEXT",
+						relativeLineNumber: 0x102,
+						sourceLine:         "NEXT HALT",
+						file:               "test.bs",
+						line:               2,
+						section:            textSection,
+						statement:          mustParse("NEXT HALT"),
+						assemblyLink:       opcodeTable["HALT"],
+					},
+				},
+				records: []*symbol{
+					{
+						symbolType:         REL,
+						label:              "",
+						relativeLineNumber: 0x100,
+						sourceLine:         "COPY 0x12345 R0",
+						file:               "test.bs",
+						line:               1,
+						section:            textSection,
+						statement:          mustParse("COPY 0x12345 R0"),
+						assemblyLink:       opcodeTable["COPY"],
+					},
+					{
+						symbolType:         REL,
+						label:              "NEXT",
+						relativeLineNumber: 0x102,
+						sourceLine:         "NEXT HALT",
+						file:               "test.bs",
+						line:               2,
+						section:            textSection,
+						statement:          mustParse("NEXT HALT"),
+						assemblyLink:       opcodeTable["HALT"],
+					},
+				},
+			},
+			wantErr: assert.NoError,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got1, err := firstPass(tt.args.sourceFile, 0x100)
+			got1, err := firstPass(tt.args.sourceFile, "test.bs", 0x100, newPassContext(nil))
 			if !tt.wantErr(t, err, fmt.Sprintf("firstPass(%v)", tt.args.sourceFile)) {
 				return
 			}
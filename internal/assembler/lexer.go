@@ -0,0 +1,160 @@
+package assembler
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenType uint8
+
+const (
+	// WORD tokens are mnemonics, labels, registers and numbers
+	WORD tokenType = iota
+	// QUOTED tokens are double quoted strings. Their text has the quotes and escapes removed
+	QUOTED
+	// PUNCT tokens are the punctuation the assembler understands, such as commas and brackets
+	PUNCT
+	// OTHER tokens are any other characters, which are only allowed in raw text like an unquoted STRING
+	OTHER
+)
+
+type token struct {
+	tokenType tokenType
+	text      string
+	// column is where the token starts on its line, counting from 1
+	column int
+}
+
+// end is the column just after the token. Quoted tokens are measured from their text, so their end is only a
+// lower bound
+func (t token) end() int {
+	return t.column + len([]rune(t.text))
+}
+
+// punctuation is every character that makes a PUNCT token on its own
+const punctuation = ",[]()+-*/&|"
+
+// shiftOperators are the PUNCT tokens two characters long
+var shiftOperators = []string{"<<", ">>"}
+
+func isWordChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// tokenize splits a line of source into tokens. It returns the tokens along with the index in line where its
+// comment starts, or the length of line if there is no comment
+func tokenize(line string) ([]token, int, error) {
+	var tokens []token
+	runes := []rune(line)
+	// Columns count characters, but the comment index is in bytes
+	byteIdx := 0
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == ';':
+			return tokens, byteIdx, nil
+		case unicode.IsSpace(r):
+			i++
+			byteIdx += len(string(r))
+		case r == '%' && strings.HasPrefix(string(runes[i:]), localLabelPrefix) && i+2 < len(runes) && isWordChar(runes[i+2]):
+			// Local labels in macros are words that start with %%
+			start := i
+			i += 2
+			for i < len(runes) && isWordChar(runes[i]) {
+				i++
+			}
+			text := string(runes[start:i])
+			tokens = append(tokens, token{tokenType: WORD, text: text, column: start + 1})
+			byteIdx += len(text)
+		case isWordChar(r):
+			start := i
+			for i < len(runes) && isWordChar(runes[i]) {
+				i++
+			}
+			text := string(runes[start:i])
+			tokens = append(tokens, token{tokenType: WORD, text: text, column: start + 1})
+			byteIdx += len(text)
+		case r == '"':
+			start := i
+			text, length, err := unquote(runes[i:])
+			if err != nil {
+				return nil, 0, errorAt(start+1, "%v", err)
+			}
+			i += length
+			byteIdx += len(string(runes[start:i]))
+			tokens = append(tokens, token{tokenType: QUOTED, text: text, column: start + 1})
+		case i+1 < len(runes) && contains(shiftOperators, string(runes[i:i+2])):
+			tokens = append(tokens, token{tokenType: PUNCT, text: string(runes[i : i+2]), column: i + 1})
+			i += 2
+			byteIdx += 2
+		case strings.ContainsRune(punctuation, r):
+			tokens = append(tokens, token{tokenType: PUNCT, text: string(r), column: i + 1})
+			i++
+			byteIdx += len(string(r))
+		default:
+			tokens = append(tokens, token{tokenType: OTHER, text: string(r), column: i + 1})
+			i++
+			byteIdx += len(string(r))
+		}
+	}
+	return tokens, len(line), nil
+}
+
+// unquote reads the double quoted string at the start of runes, returning its text and how many runes it took up
+func unquote(runes []rune) (string, int, error) {
+	text := &strings.Builder{}
+	for i := 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '"':
+			return text.String(), i + 1, nil
+		case '\\':
+			i++
+			if i == len(runes) {
+				break
+			}
+			switch runes[i] {
+			case 'n':
+				text.WriteRune('\n')
+			case 't':
+				text.WriteRune('\t')
+			case 'r':
+				text.WriteRune('\r')
+			case '0':
+				text.WriteRune(0)
+			case '\\', '"':
+				text.WriteRune(runes[i])
+			default:
+				return "", 0, fmt.Errorf("unknown escape \\%c", runes[i])
+			}
+		default:
+			text.WriteRune(runes[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// quote turns text back into a double quoted string that unquote reads as text
+func quote(text string) string {
+	quoted := &strings.Builder{}
+	quoted.WriteRune('"')
+	for _, r := range text {
+		switch r {
+		case '\n':
+			quoted.WriteString(`\n`)
+		case '\t':
+			quoted.WriteString(`\t`)
+		case '\r':
+			quoted.WriteString(`\r`)
+		case 0:
+			quoted.WriteString(`\0`)
+		case '\\', '"':
+			quoted.WriteRune('\\')
+			quoted.WriteRune(r)
+		default:
+			quoted.WriteRune(r)
+		}
+	}
+	quoted.WriteRune('"')
+	return quoted.String()
+}
@@ -0,0 +1,102 @@
+package assembler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_tokenize(t *testing.T) {
+	tests := []struct {
+		name       string
+		line       string
+		want       []token
+		commentIdx int
+		wantErr    assert.ErrorAssertionFunc
+	}{
+		{
+			name:       "blank line",
+			line:       "",
+			want:       nil,
+			commentIdx: 0,
+			wantErr:    assert.NoError,
+		},
+		{
+			name: "tabs and repeated spaces",
+			line: "\tADD  R0\t R1",
+			want: []token{
+				{tokenType: WORD, text: "ADD", column: 2},
+				{tokenType: WORD, text: "R0", column: 7},
+				{tokenType: WORD, text: "R1", column: 11},
+			},
+			commentIdx: 12,
+			wantErr:    assert.NoError,
+		},
+		{
+			name: "trailing comment",
+			line: "HALT ; stop here, [really]",
+			want: []token{
+				{tokenType: WORD, text: "HALT", column: 1},
+			},
+			commentIdx: 5,
+			wantErr:    assert.NoError,
+		},
+		{
+			name: "punctuation",
+			line: "LOAD R0,[R1+4]+",
+			want: []token{
+				{tokenType: WORD, text: "LOAD", column: 1},
+				{tokenType: WORD, text: "R0", column: 6},
+				{tokenType: PUNCT, text: ",", column: 8},
+				{tokenType: PUNCT, text: "[", column: 9},
+				{tokenType: WORD, text: "R1", column: 10},
+				{tokenType: PUNCT, text: "+", column: 12},
+				{tokenType: WORD, text: "4", column: 13},
+				{tokenType: PUNCT, text: "]", column: 14},
+				{tokenType: PUNCT, text: "+", column: 15},
+			},
+			commentIdx: 15,
+			wantErr:    assert.NoError,
+		},
+		{
+			name: "quoted string keeps comment characters",
+			line: `STRING "a; b\t\"c\"\n" ; comment`,
+			want: []token{
+				{tokenType: WORD, text: "STRING", column: 1},
+				{tokenType: QUOTED, text: "a; b\t\"c\"\n", column: 8},
+			},
+			commentIdx: 23,
+			wantErr:    assert.NoError,
+		},
+		{
+			name: "other characters",
+			line: "STRING Hi!",
+			want: []token{
+				{tokenType: WORD, text: "STRING", column: 1},
+				{tokenType: WORD, text: "Hi", column: 8},
+				{tokenType: OTHER, text: "!", column: 10},
+			},
+			commentIdx: 10,
+			wantErr:    assert.NoError,
+		},
+		{
+			name:    "unterminated string",
+			line:    `STRING "hello`,
+			wantErr: assert.Error,
+		},
+		{
+			name:    "unknown escape",
+			line:    `STRING "\q"`,
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, commentIdx, err := tokenize(tt.line)
+			if !tt.wantErr(t, err) || err != nil {
+				return
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.commentIdx, commentIdx)
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package assembler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// localLabelPrefix starts a label that is local to one expansion of a macro, like %%loop
+const localLabelPrefix = "%%"
+
+// macroDepthLimit is how deeply macros can use other macros, which stops a macro that uses itself expanding forever
+const macroDepthLimit = 64
+
+// macro is a block of source defined between MACRO and ENDM that is copied in wherever its name is used
+type macro struct {
+	name   string
+	params []string
+	body   []string
+	// file and line are where the macro was defined
+	file string
+	line int
+}
+
+// passContext is the state shared by the first pass of every file in a program
+type passContext struct {
+	includePaths []string
+	macros       map[string]*macro
+	// imported holds the files imported so far, so each is only read once
+	imported map[string]bool
+	// expansions counts the macros expanded so far, which gives each expansion its own local labels
+	expansions int
+	depth      int
+}
+
+func newPassContext(includePaths []string) *passContext {
+	return &passContext{
+		includePaths: includePaths,
+		macros:       map[string]*macro{},
+		imported:     map[string]bool{},
+	}
+}
+
+// isKeyword reports whether word starts a statement, counting the names of macros defined so far
+func (c *passContext) isKeyword(word string) bool {
+	if _, ok := c.macros[word]; ok {
+		return true
+	}
+	return isKeyword(word)
+}
+
+// newMacro starts a macro from its "MACRO name param, param" line. The body is added as it is read
+func (c *passContext) newMacro(stmt *statement, file string, line int) (*macro, error) {
+	if stmt.label != "" {
+		return nil, errorAt(stmt.labelColumn, "MACRO can't have a label")
+	}
+	if len(stmt.operands) == 0 {
+		return nil, errorAt(stmt.column, "MACRO needs a name")
+	}
+	name, ok := stmt.operands[0].word()
+	if !ok || strings.HasPrefix(name, localLabelPrefix) {
+		return nil, errorAt(stmt.operands[0].column(), "invalid macro name %q", stmt.operands[0])
+	}
+	if existing, ok := c.macros[name]; ok {
+		return nil, errorAt(stmt.operands[0].column(), "macro %q is already defined at %s:%d", name, existing.file, existing.line)
+	}
+	if isKeyword(name) {
+		return nil, errorAt(stmt.operands[0].column(), "macro %q has the same name as an instruction", name)
+	}
+	m := &macro{
+		name: name,
+		file: file,
+		line: line,
+	}
+	for _, o := range stmt.operands[1:] {
+		param, ok := o.word()
+		if !ok || strings.HasPrefix(param, localLabelPrefix) {
+			return nil, errorAt(o.column(), "invalid parameter %q", o)
+		}
+		if _, ok := registerTable[param]; ok {
+			return nil, errorAt(o.column(), "parameter %q has the same name as a register", param)
+		}
+		for _, p := range m.params {
+			if p == param {
+				return nil, errorAt(o.column(), "parameter %q is named twice", param)
+			}
+		}
+		m.params = append(m.params, param)
+	}
+	return m, nil
+}
+
+// expand gives the lines of the macro with args in place of its parameters. Local labels are renamed after the
+// expansion, so each use of the macro has its own
+func (m *macro) expand(args []operand, expansion int) ([]string, error) {
+	if len(args) != len(m.params) {
+		return nil, fmt.Errorf("macro %q takes %d args but was given %d", m.name, len(m.params), len(args))
+	}
+	lines := make([]string, len(m.body))
+	for i, line := range m.body {
+		tokens, _, err := tokenize(line)
+		if err != nil {
+			// Leave the line as it is for the first pass to report
+			lines[i] = line
+			continue
+		}
+		runes := []rune(line)
+		expanded := &strings.Builder{}
+		copied := 0
+		for _, t := range tokens {
+			if t.tokenType != WORD {
+				continue
+			}
+			replacement, ok := m.substitute(t.text, args, expansion)
+			if !ok {
+				continue
+			}
+			start := t.column - 1
+			expanded.WriteString(string(runes[copied:start]))
+			expanded.WriteString(replacement)
+			copied = start + len([]rune(t.text))
+		}
+		expanded.WriteString(string(runes[copied:]))
+		lines[i] = expanded.String()
+	}
+	return lines, nil
+}
+
+// substitute gives what a word in the body of the macro becomes when it's expanded, if it changes at all
+func (m *macro) substitute(word string, args []operand, expansion int) (string, bool) {
+	if strings.HasPrefix(word, localLabelPrefix) {
+		return fmt.Sprintf("%s%d_%s", localLabelPrefix, expansion, strings.TrimPrefix(word, localLabelPrefix)), true
+	}
+	for i, param := range m.params {
+		if word == param {
+			return args[i].source(), true
+		}
+	}
+	return "", false
+}
+
+// localLabel finds a local label used in a statement
+func localLabel(stmt *statement) (string, int, bool) {
+	if strings.HasPrefix(stmt.label, localLabelPrefix) {
+		return stmt.label, stmt.labelColumn, true
+	}
+	for _, o := range stmt.operands {
+		for _, t := range o {
+			if t.tokenType == WORD && strings.HasPrefix(t.text, localLabelPrefix) {
+				return t.text, t.column, true
+			}
+		}
+	}
+	return "", 0, false
+}
@@ -0,0 +1,119 @@
+package assembler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func Test_macro_expand(t *testing.T) {
+	m := &macro{
+		name:   "GREET",
+		params: []string{"WHO", "REG"},
+		body: []string{
+			"%%again ADDRESS WHO REG ; WHO stays in the comment",
+			`    STRING "WHO" ; so does REG`,
+			"JMP %%again",
+		},
+	}
+	args := []operand{
+		{{tokenType: WORD, text: "NAMES"}, {tokenType: PUNCT, text: "+"}, {tokenType: WORD, text: "2"}},
+		{{tokenType: WORD, text: "R1"}},
+	}
+	got, err := m.expand(args, 7)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{
+		"%%7_again ADDRESS NAMES + 2 R1 ; WHO stays in the comment",
+		`    STRING "WHO" ; so does REG`,
+		"JMP %%7_again",
+	}, got)
+	t.Run("wrong number of args", func(t *testing.T) {
+		_, err := m.expand(args[:1], 8)
+		assert.Error(t, err)
+	})
+	t.Run("quoted args", func(t *testing.T) {
+		m := &macro{name: "SAY", params: []string{"TEXT"}, body: []string{"STRING TEXT"}}
+		got, err := m.expand([]operand{{{tokenType: QUOTED, text: "a \"b\"\n"}}}, 1)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, []string{`STRING "a \"b\"\n"`}, got)
+	})
+}
+
+func TestAssemble_macros(t *testing.T) {
+	_, b, _, _ := runtime.Caller(0)
+	includes := []string{filepath.Join(filepath.Dir(b), "..", "..", "lib")}
+	t.Run("labels on macros", func(t *testing.T) {
+		src := "MACRO ONE\nCOPY 1 R0\nENDM\nJMP START\nSTART ONE\nHALT"
+		got, err := AssembleString(src, includes)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, []uint32{0x0CF00101, 0x03F00001, 0x00000000}, got.Blocks[0].Words)
+	})
+	tests := []struct {
+		name   string
+		source string
+		want   Diagnostics
+	}{
+		{
+			name:   "missing ENDM",
+			source: "MACRO FOREVER\nHALT",
+			want: Diagnostics{
+				{File: inputName, Line: 1, Severity: SeverityError, Message: `macro "FOREVER" is missing ENDM`},
+			},
+		},
+		{
+			name:   "ENDM without MACRO",
+			source: "HALT\n  ENDM",
+			want: Diagnostics{
+				{File: inputName, Line: 2, Column: 3, Severity: SeverityError, Message: "ENDM without MACRO"},
+			},
+		},
+		{
+			name:   "wrong number of args",
+			source: "MACRO TWO A, B\nCOPY A B\nENDM\nTWO R0",
+			want: Diagnostics{
+				{File: inputName, Line: 4, Column: 1, Severity: SeverityError, Message: `macro "TWO" takes 2 args but was given 1`},
+			},
+		},
+		{
+			name:   "local label outside a macro",
+			source: "JMP %%nowhere",
+			want: Diagnostics{
+				{File: inputName, Line: 1, Column: 5, Severity: SeverityError, Message: `local label "%%nowhere" can only be used in a macro`},
+			},
+		},
+		{
+			name:   "macro named after an instruction",
+			source: "MACRO HALT\nENDM",
+			want: Diagnostics{
+				{File: inputName, Line: 1, Column: 7, Severity: SeverityError, Message: `macro "HALT" has the same name as an instruction`},
+			},
+		},
+		{
+			name:   "macro that uses itself",
+			source: "MACRO AGAIN\nAGAIN\nENDM\nAGAIN",
+			want: Diagnostics{
+				{File: inputName, Line: 4, Column: 1, Severity: SeverityError, Message: `in macro "AGAIN": macro "AGAIN" is nested too deeply, does it use itself?`},
+			},
+		},
+		{
+			name:   "errors inside a macro point at its use",
+			source: "MACRO BAD\nDUP HALT\nENDM\n  BAD\n  BAD",
+			want: Diagnostics{
+				{File: inputName, Line: 5, Column: 3, Severity: SeverityError, Message: `in macro "BAD": duplicate symbol "DUP", first defined at <input>:4`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := AssembleString(tt.source, includes)
+			assert.Equal(t, tt.want, err)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package assembler
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// operand is the group of tokens making up one argument to an instruction or directive
+type operand []token
+
+func (o operand) String() string {
+	texts := make([]string, len(o))
+	for i, t := range o {
+		texts[i] = t.text
+	}
+	return strings.Join(texts, " ")
+}
+
+// word gives the text of an operand that is a single word, like a register, symbol or number
+func (o operand) word() (string, bool) {
+	if len(o) != 1 || o[0].tokenType != WORD {
+		return "", false
+	}
+	return o[0].text, true
+}
+
+// source writes the operand back out as source code
+func (o operand) source() string {
+	texts := make([]string, len(o))
+	for i, t := range o {
+		texts[i] = t.text
+		if t.tokenType == QUOTED {
+			texts[i] = quote(t.text)
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// column gives where the operand starts on its line
+func (o operand) column() int {
+	if len(o) == 0 {
+		return 0
+	}
+	return o[0].column
+}
+
+// statement is a parsed line of source
+type statement struct {
+	label    string
+	mnemonic string
+	operands []operand
+	// labelColumn and column are where the label and mnemonic start on the line
+	labelColumn int
+	column      int
+	// text is the source after the mnemonic, without the comment or surrounding whitespace
+	text string
+	// comment is set when the line has a comment
+	comment bool
+}
+
+// isKeyword reports whether word starts a statement rather than labelling it
+func isKeyword(word string) bool {
+	if _, ok := opcodeTable[word]; ok {
+		return true
+	}
+	if _, ok := directiveTable[word]; ok {
+		return true
+	}
+	return word == "IMPORT" || word == "MACRO" || word == "ENDM"
+}
+
+// isSymbolName reports whether word can name a label or constant. Words starting with a digit are numbers
+func isSymbolName(word string) bool {
+	r, _ := utf8.DecodeRuneInString(word)
+	return !unicode.IsDigit(r)
+}
+
+// parseStatement parses a line of source. A line is an optional label, then a mnemonic followed by its operands.
+// Operands are separated by commas or whitespace, and brackets group everything inside them into one operand
+func parseStatement(line string) (*statement, error) {
+	return parseLine(line, isKeyword)
+}
+
+// parseLine parses a line of source, using keyword to tell mnemonics from labels
+func parseLine(line string, keyword func(word string) bool) (*statement, error) {
+	tokens, commentIdx, err := tokenize(line)
+	if err != nil {
+		return nil, err
+	}
+	stmt := &statement{
+		comment: commentIdx < len(line),
+	}
+	if len(tokens) == 0 {
+		return stmt, nil
+	}
+	if tokens[0].tokenType != WORD {
+		return nil, errorAt(tokens[0].column, "unexpected %q", tokens[0].text)
+	}
+	if !keyword(tokens[0].text) {
+		if !isSymbolName(tokens[0].text) {
+			// Numbers are read as literals, so a label that looks like one could never be used
+			return nil, errorAt(tokens[0].column, "label %q can't start with a digit", tokens[0].text)
+		}
+		stmt.label = tokens[0].text
+		stmt.labelColumn = tokens[0].column
+		tokens = tokens[1:]
+		if len(tokens) == 0 {
+			return stmt, nil
+		}
+		if tokens[0].tokenType != WORD {
+			return nil, errorAt(tokens[0].column, "unexpected %q", tokens[0].text)
+		}
+	}
+	stmt.mnemonic = tokens[0].text
+	stmt.column = tokens[0].column
+	tokens = tokens[1:]
+	if len(tokens) > 0 {
+		runes := []rune(line[:commentIdx])
+		stmt.text = strings.TrimSpace(string(runes[tokens[0].column-1:]))
+	}
+	stmt.operands, err = groupOperands(tokens)
+	if err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// closingBracket pairs each opening bracket with the bracket that closes it
+var closingBracket = map[string]string{
+	"[": "]",
+	"(": ")",
+}
+
+// groupOperands splits the tokens after a mnemonic into operands
+func groupOperands(tokens []token) ([]operand, error) {
+	var operands []operand
+	var current operand
+	// open holds the brackets that haven't been closed yet
+	var open []token
+	for i, t := range tokens {
+		if t.tokenType == PUNCT && t.text == "," && len(open) == 0 {
+			if len(current) == 0 {
+				return nil, errorAt(t.column, "missing operand before ','")
+			}
+			operands = append(operands, current)
+			current = nil
+			continue
+		}
+		if len(open) == 0 && len(current) > 0 && !joins(current[len(current)-1], t, tokens[i+1:]) {
+			operands = append(operands, current)
+			current = nil
+		}
+		if t.tokenType == PUNCT {
+			if _, ok := closingBracket[t.text]; ok {
+				open = append(open, t)
+			} else if t.text == "]" || t.text == ")" {
+				if len(open) == 0 || closingBracket[open[len(open)-1].text] != t.text {
+					return nil, errorAt(t.column, "unexpected '%s'", t.text)
+				}
+				open = open[:len(open)-1]
+			}
+		}
+		current = append(current, t)
+	}
+	if len(open) > 0 {
+		unclosed := open[len(open)-1]
+		return nil, errorAt(unclosed.column, "missing '%s'", closingBracket[unclosed.text])
+	}
+	if len(current) == 0 && len(tokens) > 0 {
+		last := tokens[len(tokens)-1]
+		return nil, errorAt(last.column, "missing operand after ','")
+	}
+	if len(current) > 0 {
+		operands = append(operands, current)
+	}
+	return operands, nil
+}
+
+// joins reports whether next carries on the same operand as prev, which is the case either side of an operator.
+// A + or - with a space before it but not after is the sign of a new operand, as in ADD R1 -1, rather than a binary
+// operator
+func joins(prev, next token, rest []token) bool {
+	if isOperator(prev) {
+		return true
+	}
+	if !isOperator(next) {
+		return false
+	}
+	if next.text != "+" && next.text != "-" {
+		return true
+	}
+	spaceBefore := next.column > prev.end()
+	spaceAfter := len(rest) > 0 && rest[0].column > next.end()
+	return !spaceBefore || spaceAfter
+}
@@ -0,0 +1,149 @@
+package assembler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// mustParse parses a line for test fixtures, panicking if it isn't valid
+func mustParse(line string) *statement {
+	stmt, err := parseStatement(line)
+	if err != nil {
+		panic(err)
+	}
+	return stmt
+}
+
+func Test_parseStatement(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		label    string
+		mnemonic string
+		operands []string
+		text     string
+		comment  bool
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{
+			name:    "blank line",
+			line:    "   \t",
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "comment only",
+			line:    "  ; nothing to see",
+			comment: true,
+			wantErr: assert.NoError,
+		},
+		{
+			name:     "label with whitespace",
+			line:     "LOOP\tADD   R0  R1",
+			label:    "LOOP",
+			mnemonic: "ADD",
+			operands: []string{"R0", "R1"},
+			text:     "R0  R1",
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "commas and trailing comment",
+			line:     "  COPY 0x10, R2 ; set up",
+			mnemonic: "COPY",
+			operands: []string{"0x10", "R2"},
+			text:     "0x10, R2",
+			comment:  true,
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "memory operand",
+			line:     "STORE R1, [ FP - 2 ]+",
+			mnemonic: "STORE",
+			operands: []string{"R1", "[ FP - 2 ] +"},
+			text:     "R1, [ FP - 2 ]+",
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "quoted string",
+			line:     `GREETING STRING "hello, world" ; greeting`,
+			label:    "GREETING",
+			mnemonic: "STRING",
+			operands: []string{"hello, world"},
+			text:     `"hello, world"`,
+			comment:  true,
+			wantErr:  assert.NoError,
+		},
+		{
+			name:    "label on its own",
+			line:    "START ; entry point",
+			label:   "START",
+			comment: true,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "missing operand",
+			line:    "ADD R0,, R1",
+			wantErr: assert.Error,
+		},
+		{
+			name:    "trailing comma",
+			line:    "ADD R0, R1,",
+			wantErr: assert.Error,
+		},
+		{
+			name:    "unclosed bracket",
+			line:    "LOAD R0, [R1",
+			wantErr: assert.Error,
+		},
+		{
+			name:    "starts with punctuation",
+			line:    ", ADD R0 R1",
+			wantErr: assert.Error,
+		},
+		{
+			name:     "negative immediate after a register",
+			line:     "ADD R1 -1",
+			mnemonic: "ADD",
+			operands: []string{"R1", "- 1"},
+			text:     "R1 -1",
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "binary operators",
+			line:     "ADD 2 - 1 R0",
+			mnemonic: "ADD",
+			operands: []string{"2 - 1", "R0"},
+			text:     "2 - 1 R0",
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "binary operators without spaces",
+			line:     "ADD 2-1 R0",
+			mnemonic: "ADD",
+			operands: []string{"2 - 1", "R0"},
+			text:     "2-1 R0",
+			wantErr:  assert.NoError,
+		},
+		{
+			name:    "label starting with a digit",
+			line:    "123 HALT",
+			wantErr: assert.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStatement(tt.line)
+			if !tt.wantErr(t, err) || err != nil {
+				return
+			}
+			assert.Equal(t, tt.label, got.label)
+			assert.Equal(t, tt.mnemonic, got.mnemonic)
+			var operands []string
+			for _, o := range got.operands {
+				operands = append(operands, o.String())
+			}
+			assert.Equal(t, tt.operands, operands)
+			assert.Equal(t, tt.text, got.text)
+			assert.Equal(t, tt.comment, got.comment)
+		})
+	}
+}
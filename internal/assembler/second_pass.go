@@ -1,29 +1,83 @@
 package assembler
 
-import "github.com/ThreeToes/blogvm/internal/executable"
+import (
+	"errors"
+	"github.com/ThreeToes/blogvm/internal/executable"
+)
+
+// Protection given to the blocks holding instructions and data
+const (
+	codeProtection = executable.BLOCK_READ | executable.BLOCK_EXECUTE
+	dataProtection = executable.BLOCK_READ | executable.BLOCK_WRITE
+)
+
+// protectionFor gives the protection of the words a record assembles to
+func protectionFor(a assemblable) uint32 {
+	if d, ok := a.(*directive); ok && d.data {
+		return dataProtection
+	}
+	return codeProtection
+}
 
 func secondPass(firstPass *firstPassFile) (*executable.LoadableFile, error) {
 	ret := &executable.LoadableFile{
-		BlockCount: 0x1,
-		Flags:      0,
+		BlockCount: 0,
+		Flags:      executable.FLAG_BLOCK_PROTECTION,
 		Blocks:     nil,
 	}
-	b := &executable.MemoryBlock{
-		Address:   0x100,
-		BlockSize: 0,
-		Words:     nil,
-	}
+	// Code and data are split into separate blocks so the code can be loaded read only, and a new block starts
+	// wherever an ORG or a section leaves a gap
+	var b *executable.MemoryBlock
+	var diagnostics Diagnostics
+	// cycles holds the cycles of constants already reported
+	cycles := map[string]bool{}
 	for _, rec := range firstPass.records {
 		if rec.assemblyLink == nil {
 			continue
 		}
+		if d, ok := rec.assemblyLink.(*directive); ok && d.uninitialised && rec.section == bssSection {
+			// Space in the bss section is left out of the file, so only check its size rather than filling it
+			if _, err := placementValue(rec.statement, firstPass.symbolTable); err != nil {
+				diagnostics = append(diagnostics, diagnose(rec, err))
+			}
+			continue
+		}
 		words, err := rec.assemble(firstPass.symbolTable)
+		var cycle *constantCycle
+		if errors.As(err, &cycle) {
+			// A cycle of constants is reported once, at the first of their definitions. Lines using them fail
+			// along with it
+			if d, ok := rec.assemblyLink.(*directive); ok && d.constant && cycle.involves(rec.label) && !cycles[cycle.key()] {
+				cycles[cycle.key()] = true
+				_, column, _ := constantName(rec.statement)
+				diagnostics = append(diagnostics, diagnose(rec, errorAt(column, "%v", cycle)))
+			}
+			continue
+		}
 		if err != nil {
-			return nil, err
+			// Keep going so every problem is reported at once
+			diagnostics = append(diagnostics, diagnose(rec, err))
+			continue
+		}
+		if len(words) == 0 || rec.section == bssSection {
+			continue
+		}
+		protection := protectionFor(rec.assemblyLink)
+		if b == nil || b.Protection != protection || b.Address+b.BlockSize != rec.relativeLineNumber {
+			b = &executable.MemoryBlock{
+				Address:    rec.relativeLineNumber,
+				BlockSize:  0,
+				Protection: protection,
+				Words:      nil,
+			}
+			ret.Blocks = append(ret.Blocks, b)
 		}
 		b.Words = append(b.Words, words...)
+		b.BlockSize = uint32(len(b.Words))
+	}
+	if diagnostics.HasErrors() {
+		return nil, diagnostics
 	}
-	ret.Blocks = append(ret.Blocks, b)
-	b.BlockSize = uint32(len(b.Words))
+	ret.BlockCount = uint32(len(ret.Blocks))
 	return ret, nil
 }
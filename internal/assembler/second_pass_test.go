@@ -26,8 +26,9 @@ func Test_secondPass(t *testing.T) {
 						{
 							symbolType:         REL,
 							label:              "",
-							relativeLineNumber: 0,
+							relativeLineNumber: 0x100,
 							sourceLine:         "ADD R1 R2",
+							statement:          mustParse("ADD R1 R2"),
 							assemblyLink:       opcodeTable["ADD"],
 						},
 					},
@@ -35,11 +36,12 @@ func Test_secondPass(t *testing.T) {
 			},
 			want: &executable.LoadableFile{
 				BlockCount: 0x01,
-				Flags:      0x00,
+				Flags:      executable.FLAG_BLOCK_PROTECTION,
 				Blocks: []*executable.MemoryBlock{
 					{
-						Address:   0x100,
-						BlockSize: 0x01,
+						Address:    0x100,
+						BlockSize:  0x01,
+						Protection: codeProtection,
 						Words: []uint32{
 							0x04120000,
 						},
@@ -48,6 +50,125 @@ func Test_secondPass(t *testing.T) {
 			},
 			wantErr: assert.NoError,
 		},
+		{
+			name: "code and data in separate blocks",
+			args: args{
+				firstPass: &firstPassFile{
+					symbolTable: symbols{},
+					records: []*symbol{
+						{
+							symbolType:         REL,
+							relativeLineNumber: 0x100,
+							sourceLine:         "ADD R1 R2",
+							statement:          mustParse("ADD R1 R2"),
+							assemblyLink:       opcodeTable["ADD"],
+						},
+						{
+							symbolType:         REL,
+							label:              "X",
+							relativeLineNumber: 0x101,
+							sourceLine:         "X WORD 0x07",
+							statement:          mustParse("X WORD 0x07"),
+							assemblyLink:       directiveTable["WORD"],
+						},
+						{
+							symbolType:         REL,
+							relativeLineNumber: 0x102,
+							sourceLine:         "STRING hi",
+							statement:          mustParse("STRING hi"),
+							assemblyLink:       directiveTable["STRING"],
+						},
+						{
+							symbolType:         REL,
+							relativeLineNumber: 0x105,
+							sourceLine:         "HALT",
+							statement:          mustParse("HALT"),
+							assemblyLink:       opcodeTable["HALT"],
+						},
+					},
+				},
+			},
+			want: &executable.LoadableFile{
+				BlockCount: 0x03,
+				Flags:      executable.FLAG_BLOCK_PROTECTION,
+				Blocks: []*executable.MemoryBlock{
+					{
+						Address:    0x100,
+						BlockSize:  0x01,
+						Protection: codeProtection,
+						Words:      []uint32{0x04120000},
+					},
+					{
+						Address:    0x101,
+						BlockSize:  0x04,
+						Protection: dataProtection,
+						Words:      []uint32{0x07, 'h', 'i', 0x00},
+					},
+					{
+						Address:    0x105,
+						BlockSize:  0x01,
+						Protection: codeProtection,
+						Words:      []uint32{0x00000000},
+					},
+				},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "gaps start new blocks and bss is left out",
+			args: args{
+				firstPass: &firstPassFile{
+					symbolTable: symbols{},
+					records: []*symbol{
+						{
+							symbolType:         REL,
+							relativeLineNumber: 0x100,
+							sourceLine:         "HALT",
+							section:            textSection,
+							statement:          mustParse("HALT"),
+							assemblyLink:       opcodeTable["HALT"],
+						},
+						{
+							symbolType:         REL,
+							relativeLineNumber: 0x2000,
+							sourceLine:         "HALT",
+							section:            textSection,
+							statement:          mustParse("HALT"),
+							assemblyLink:       opcodeTable["HALT"],
+						},
+						// Far too big to fill in, so bss mustn't be assembled like the other sections
+						{
+							symbolType:         REL,
+							label:              "BUFFER",
+							relativeLineNumber: 0x2001,
+							sourceLine:         "BUFFER RESERVE 0x10000000",
+							section:            bssSection,
+							statement:          mustParse("BUFFER RESERVE 0x10000000"),
+							assemblyLink:       directiveTable["RESERVE"],
+						},
+					},
+				},
+			},
+			want: &executable.LoadableFile{
+				BlockCount: 0x02,
+				Flags:      executable.FLAG_BLOCK_PROTECTION,
+				Blocks: []*executable.MemoryBlock{
+					{
+						Address:    0x100,
+						BlockSize:  0x01,
+						Protection: codeProtection,
+						Words:      []uint32{0x00000000},
+					},
+					{
+						Address:    0x2000,
+						BlockSize:  0x01,
+						Protection: codeProtection,
+						Words:      []uint32{0x00000000},
+					},
+				},
+			},
+			wantErr: assert.NoError,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
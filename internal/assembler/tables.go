@@ -1,82 +1,266 @@
 package assembler
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 )
 
 type assemblable interface {
-	calculateSize(sourceLine string) uint32
-	assemble(sourceLine string, symbolTable symbols) ([]uint32, error)
+	calculateSize(stmt *statement, symbolTable symbols) uint32
+	assemble(stmt *statement, symbolTable symbols) ([]uint32, error)
 }
 
+const (
+	// extendedImmediate is set in the opcode byte when the immediate data follows in the next word
+	extendedImmediate = 0x80
+	// maxShortImmediate is the largest value that fits in the immediate field of an instruction
+	maxShortImmediate = 0xFFFF
+)
+
 type opCode struct {
 	mnemonic     string
 	opcode       uint8
 	hasI1        bool
 	hasI2        bool
 	allowSymbols bool
+	// memoryOperand instructions take a register and a [base + offset] operand instead of I1 and I2
+	memoryOperand bool
+	// postIncrement is the opcode used when the memory operand is written as [base + offset]+
+	postIncrement uint8
 }
 
 func (o *opCode) instructionMask() uint32 {
 	return uint32(o.opcode) << 24
 }
 
-func (o *opCode) calculateSize(sourceLine string) uint32 {
-	// NOTE: could change in the future
+func (o *opCode) calculateSize(stmt *statement, symbolTable symbols) uint32 {
+	if o.memoryOperand {
+		return o.calculateMemorySize(stmt, symbolTable)
+	}
+	for _, arg := range stmt.operands {
+		if word, ok := arg.word(); ok {
+			if _, ok := registerTable[word]; ok {
+				continue
+			}
+		}
+		if p, ok := constantValueOf(arg, symbolTable); ok && p > maxShortImmediate {
+			return 2
+		}
+	}
 	return 1
 }
 
-func (o *opCode) assemble(sourceLine string, symbolTable symbols) ([]uint32, error) {
-	curIdx := 1
-	instruction := uint32(o.opcode) << 24
-	cols := strings.Split(sourceLine, " ")
-	if cols[0] != o.mnemonic {
-		curIdx = 2
+// constantValueOf gives the value of an operand when it doesn't depend on any labels. Values that don't fit in the
+// immediate field need the extended form, but labels are addresses, which are expected to fit. Deciding the size
+// without them means every line's size is known before any addresses are
+func constantValueOf(o operand, symbolTable symbols) (uint32, bool) {
+	value, usesLabels, err := evaluate(o, symbolTable, true)
+	if err != nil || usesLabels {
+		return 0, false
+	}
+	return value, true
+}
+
+// immediate assembles an operand that isn't a register into its immediate value
+func (o *opCode) immediate(arg operand, symbolTable symbols) (uint32, error) {
+	value, _, err := evaluate(arg, symbolTable, o.allowSymbols)
+	if err != nil {
+		var srcErr *sourceError
+		if errors.As(err, &srcErr) && srcErr.column == 0 {
+			srcErr.column = arg.column()
+		}
+		return 0, err
+	}
+	return value, nil
+}
+
+// registerOperand gives the register an operand names, if it is one
+func registerOperand(o operand) (*register, bool) {
+	word, ok := o.word()
+	if !ok {
+		return nil, false
 	}
+	reg, ok := registerTable[word]
+	return reg, ok
+}
+
+func (o *opCode) assemble(stmt *statement, symbolTable symbols) ([]uint32, error) {
+	if o.memoryOperand {
+		return o.assembleMemory(stmt, symbolTable)
+	}
+	curIdx := 0
+	instruction := o.instructionMask()
+	immediate := uint32(0)
+	i1Immediate := false
 	if o.hasI1 {
-		if len(cols) <= curIdx {
+		if len(stmt.operands) <= curIdx {
 			return nil, fmt.Errorf("not enough args supplied")
 		}
-		arg := cols[curIdx]
-		if reg, ok := registerTable[arg]; ok {
+		arg := stmt.operands[curIdx]
+		if reg, ok := registerOperand(arg); ok {
 			nibble := uint32(reg.nibble)
 			instruction = instruction | (nibble << 20)
-		} else if symbol, ok := symbolTable[arg]; ok && o.allowSymbols {
-			// Try resolving a symbol
-			instruction = instruction | (0xF << 20) | (symbol.relativeLineNumber & 0xFFFF)
 		} else {
-			// Try to parse immediate data
-			p, err := parseLiteral(arg)
+			// Anything else is immediate data, be it a literal, a symbol or an expression
+			p, err := o.immediate(arg, symbolTable)
 			if err != nil {
-				return nil, fmt.Errorf("unrecognised symbol %q", arg)
+				return nil, err
 			}
-			instruction = instruction | (uint32(0xF) << 20) | (p & 0xFFFF)
+			instruction = instruction | (uint32(0xF) << 20)
+			immediate = p
+			i1Immediate = true
 		}
 		curIdx++
 	}
 	if o.hasI2 {
-
-		if len(cols) <= curIdx {
+		if len(stmt.operands) <= curIdx {
 			return nil, fmt.Errorf("not enough args supplied")
 		}
-		arg := cols[curIdx]
-		if reg, ok := registerTable[arg]; ok {
+		arg := stmt.operands[curIdx]
+		if reg, ok := registerOperand(arg); ok {
 			nibble := uint32(reg.nibble)
 			instruction = instruction | (nibble << 16)
-		} else if symbol, ok := symbolTable[arg]; ok && o.allowSymbols {
-			instruction = instruction | (0xF << 16) | (symbol.relativeLineNumber & 0xFFFF)
 		} else {
-			p, err := parseLiteral(arg)
+			// Both operands share the one immediate field
+			if i1Immediate {
+				return nil, errorAt(arg.column(), "only one operand can be immediate")
+			}
+			p, err := o.immediate(arg, symbolTable)
 			if err != nil {
 				return nil, err
 			}
-			instruction = instruction | (uint32(0xF) << 16) | (p & 0xFFFF)
+			instruction = instruction | (uint32(0xF) << 16)
+			immediate = p
 		}
+		curIdx++
+	}
+	if len(stmt.operands) > curIdx {
+		return nil, errorAt(stmt.operands[curIdx].column(), "too many args supplied")
+	}
+
+	if o.calculateSize(stmt, symbolTable) == 2 {
+		instruction = instruction | (extendedImmediate << 24)
+		return []uint32{instruction, immediate}, nil
+	}
+	if immediate > maxShortImmediate {
+		return nil, fmt.Errorf("value %#x is out of range for a short immediate", immediate)
+	}
+	return []uint32{instruction | immediate}, nil
+}
+
+type memoryOperand struct {
+	register string
+	base     string
+	// offset is the expression added to the base, which starts with its sign
+	offset        operand
+	postIncrement bool
+}
+
+// parseMemoryOperand reads the "rX, [rB + offset]" operands of a statement, where the offset is optional and a
+// trailing + means post-increment
+func parseMemoryOperand(stmt *statement) (*memoryOperand, error) {
+	if len(stmt.operands) != 2 {
+		return nil, fmt.Errorf("invalid memory operand %q", stmt.text)
+	}
+	register, ok := stmt.operands[0].word()
+	if !ok {
+		return nil, fmt.Errorf("invalid memory operand %q", stmt.text)
+	}
+	ret := &memoryOperand{
+		register: register,
+	}
+	tokens := stmt.operands[1]
+	if len(tokens) > 0 && tokens[len(tokens)-1].text == "+" {
+		ret.postIncrement = true
+		tokens = tokens[:len(tokens)-1]
+	}
+	isPunct := func(idx int, text string) bool {
+		return tokens[idx].tokenType == PUNCT && tokens[idx].text == text
+	}
+	if len(tokens) < 3 || !isPunct(0, "[") || tokens[1].tokenType != WORD || !isPunct(len(tokens)-1, "]") {
+		return nil, fmt.Errorf("invalid memory operand %q", stmt.text)
+	}
+	ret.base = tokens[1].text
+	inner := tokens[2 : len(tokens)-1]
+	switch {
+	case len(inner) == 0:
+	case len(inner) > 1 && isPunct(2, "+"):
+		ret.offset = operand(inner[1:])
+	case len(inner) > 1 && isPunct(2, "-"):
+		// Keep the minus so it negates the first value of the offset
+		ret.offset = operand(inner)
+	default:
+		return nil, fmt.Errorf("invalid memory operand %q", stmt.text)
+	}
+	return ret, nil
+}
+
+// resolveOffset gives the offset as a two's complement word
+func (m *memoryOperand) resolveOffset(symbolTable symbols) (uint32, error) {
+	if len(m.offset) == 0 {
+		return 0, nil
+	}
+	offset, _, err := evaluate(m.offset, symbolTable, true)
+	return offset, err
+}
+
+func (o *opCode) calculateMemorySize(stmt *statement, symbolTable symbols) uint32 {
+	operand, err := parseMemoryOperand(stmt)
+	if err != nil || len(operand.offset) == 0 {
+		return 1
+	}
+	p, ok := constantValueOf(operand.offset, symbolTable)
+	if !ok {
+		return 1
+	}
+	if signed := int32(p); signed < math.MinInt16 || signed > math.MaxInt16 {
+		return 2
+	}
+	return 1
+}
+
+// assembleMemory assembles an instruction with a memory operand. LOAD encodes the base register in I1 and the
+// destination in I2, while STORE encodes the source in I1 and the base in I2. The offset goes in the immediate data
+func (o *opCode) assembleMemory(stmt *statement, symbolTable symbols) ([]uint32, error) {
+	operand, err := parseMemoryOperand(stmt)
+	if err != nil {
+		return nil, err
+	}
+	reg, ok := registerTable[operand.register]
+	if !ok {
+		return nil, fmt.Errorf("%q is not a register", operand.register)
+	}
+	base, ok := registerTable[operand.base]
+	if !ok {
+		return nil, fmt.Errorf("%q is not a register", operand.base)
+	}
+	offset, err := operand.resolveOffset(symbolTable)
+	if err != nil {
+		return nil, err
 	}
 
-	return []uint32{instruction}, nil
+	opcode := o.opcode
+	if operand.postIncrement {
+		opcode = o.postIncrement
+	}
+	instruction := uint32(opcode) << 24
+	if o.mnemonic == "STORE" {
+		instruction = instruction | uint32(reg.nibble)<<20 | uint32(base.nibble)<<16
+	} else {
+		instruction = instruction | uint32(base.nibble)<<20 | uint32(reg.nibble)<<16
+	}
+
+	if o.calculateMemorySize(stmt, symbolTable) == 2 {
+		instruction = instruction | (extendedImmediate << 24)
+		return []uint32{instruction, offset}, nil
+	}
+	if signed := int32(offset); signed < math.MinInt16 || signed > math.MaxInt16 {
+		return nil, fmt.Errorf("offset %d is out of range for a short immediate", signed)
+	}
+	return []uint32{instruction | (offset & 0xFFFF)}, nil
 }
 
 type opcodeTableType map[string]*opCode
@@ -206,6 +390,152 @@ var opcodeTable = opcodeTableType{
 		hasI1:    false,
 		hasI2:    false,
 	},
+	"IRET": {
+		mnemonic: "IRET",
+		opcode:   0x14,
+		hasI1:    false,
+		hasI2:    false,
+	},
+	"AND": {
+		mnemonic: "AND",
+		opcode:   0x15,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"OR": {
+		mnemonic: "OR",
+		opcode:   0x16,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"XOR": {
+		mnemonic: "XOR",
+		opcode:   0x17,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"NOT": {
+		mnemonic: "NOT",
+		opcode:   0x18,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"SHL": {
+		mnemonic: "SHL",
+		opcode:   0x19,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"SHR": {
+		mnemonic: "SHR",
+		opcode:   0x1A,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"SAR": {
+		mnemonic: "SAR",
+		opcode:   0x1B,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"ROL": {
+		mnemonic: "ROL",
+		opcode:   0x1C,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"ROR": {
+		mnemonic: "ROR",
+		opcode:   0x1D,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"ADDS": {
+		mnemonic: "ADDS",
+		opcode:   0x1E,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"SUBS": {
+		mnemonic: "SUBS",
+		opcode:   0x1F,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"MULS": {
+		mnemonic: "MULS",
+		opcode:   0x20,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"DIVS": {
+		mnemonic: "DIVS",
+		opcode:   0x21,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"MOD": {
+		mnemonic: "MOD",
+		opcode:   0x22,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"LESSS": {
+		mnemonic: "LESSS",
+		opcode:   0x23,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"LTES": {
+		mnemonic: "LTES",
+		opcode:   0x24,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"GTS": {
+		mnemonic: "GTS",
+		opcode:   0x25,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"GTES": {
+		mnemonic: "GTES",
+		opcode:   0x26,
+		hasI1:    true,
+		hasI2:    true,
+	},
+	"LOAD": {
+		mnemonic:      "LOAD",
+		opcode:        0x27,
+		memoryOperand: true,
+		postIncrement: 0x29,
+		allowSymbols:  true,
+	},
+	"STORE": {
+		mnemonic:      "STORE",
+		opcode:        0x28,
+		memoryOperand: true,
+		postIncrement: 0x2A,
+		allowSymbols:  true,
+	},
+	"ENTER": {
+		mnemonic: "ENTER",
+		opcode:   0x2B,
+		hasI1:    true,
+		hasI2:    false,
+	},
+	"LEAVE": {
+		mnemonic: "LEAVE",
+		opcode:   0x2C,
+		hasI1:    false,
+		hasI2:    false,
+	},
+	"SYSCALL": {
+		mnemonic: "SYSCALL",
+		opcode:   0x2D,
+		hasI1:    true,
+		hasI2:    false,
+	},
 }
 
 func (o opcodeTableType) isMnemonic(mnem string) bool {
@@ -223,17 +553,137 @@ func (o opcodeTableType) reverseLookup(opcode uint8) *opCode {
 }
 
 type directive struct {
-	mnemonic     string
-	sizeCalc     func(sourceLine string) uint32
-	assembleFunc func(sourceLine string, symbolTable symbols) ([]uint32, error)
+	mnemonic string
+	// data directives emit words for the program to read and write rather than run
+	data bool
+	// constant directives name a value instead of emitting anything
+	constant bool
+	// origin directives move the lines after them to a new address
+	origin bool
+	// section directives switch the section the lines after them are placed in
+	section bool
+	// uninitialised directives only set aside space, which is all the bss section can hold
+	uninitialised bool
+	sizeCalc      func(stmt *statement, symbolTable symbols) uint32
+	assembleFunc  func(stmt *statement, symbolTable symbols) ([]uint32, error)
+}
+
+func (d *directive) calculateSize(stmt *statement, symbolTable symbols) uint32 {
+	return d.sizeCalc(stmt, symbolTable)
 }
 
-func (d *directive) calculateSize(sourceLine string) uint32 {
-	return d.sizeCalc(sourceLine)
+func (d *directive) assemble(stmt *statement, symbolTable symbols) ([]uint32, error) {
+	return d.assembleFunc(stmt, symbolTable)
 }
 
-func (d *directive) assemble(sourceLine string, symbolTable symbols) ([]uint32, error) {
-	return d.assembleFunc(sourceLine, symbolTable)
+// stringText gives the text of a STRING directive or IMPORT. It may be quoted, in which case escapes like \n are understood,
+// or written out as it is up to the end of the line or a comment
+func stringText(stmt *statement) string {
+	if len(stmt.operands) == 1 && len(stmt.operands[0]) == 1 && stmt.operands[0][0].tokenType == QUOTED {
+		return stmt.operands[0][0].text
+	}
+	return stmt.text
+}
+
+// constantName gives the name a constant directive defines. EQU names the constant with its label, as in
+// "WIDTH EQU 320", while DEFINE takes it as its first operand, as in "DEFINE WIDTH 320"
+func constantName(stmt *statement) (string, int, error) {
+	name, column := stmt.label, stmt.labelColumn
+	if stmt.mnemonic == "DEFINE" {
+		if stmt.label != "" {
+			return "", 0, errorAt(stmt.labelColumn, "DEFINE can't have a label")
+		}
+		if len(stmt.operands) != 2 {
+			return "", 0, errorAt(stmt.column, "DEFINE takes a name and a value")
+		}
+		word, ok := stmt.operands[0].word()
+		if !ok || isKeyword(word) || !isSymbolName(word) {
+			return "", 0, errorAt(stmt.operands[0].column(), "invalid constant name %q", stmt.operands[0])
+		}
+		name, column = word, stmt.operands[0].column()
+	} else {
+		if name == "" {
+			return "", 0, errorAt(stmt.column, "EQU needs a label to name the constant")
+		}
+		if len(stmt.operands) != 1 {
+			return "", 0, errorAt(stmt.column, "EQU takes a single value")
+		}
+	}
+	if _, ok := registerTable[name]; ok {
+		return "", 0, errorAt(column, "constant %q has the same name as a register", name)
+	}
+	return name, column, nil
+}
+
+// constantValue gives the expression a constant directive defines its constant as
+func constantValue(stmt *statement) operand {
+	if len(stmt.operands) == 0 {
+		return nil
+	}
+	return stmt.operands[len(stmt.operands)-1]
+}
+
+// assembleConstant checks that a constant's value can be worked out. Constants don't take up any memory
+func assembleConstant(stmt *statement, symbolTable symbols) ([]uint32, error) {
+	// The first pass has already checked the name with constantName
+	name := stmt.label
+	if stmt.mnemonic == "DEFINE" {
+		name, _ = stmt.operands[0].word()
+	}
+	if _, _, err := evaluateConstant(name, constantValue(stmt), symbolTable); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// Sections group the lines of a program wherever they are in the source. Text holds the code, data the variables
+// given a value and bss the space set aside with RESERVE, which isn't saved in the file
+const (
+	textSection = "text"
+	dataSection = "data"
+	bssSection  = "bss"
+)
+
+// sectionOrder is the order sections are placed in memory when they don't have an ORG. A record without a section
+// is text
+var sectionOrder = map[string]int{
+	textSection: 0,
+	dataSection: 1,
+	bssSection:  2,
+}
+
+// sectionName gives the section a SECTION directive switches to
+func sectionName(stmt *statement) (string, error) {
+	if len(stmt.operands) == 0 {
+		return "", errorAt(stmt.column, "SECTION needs a name")
+	}
+	if len(stmt.operands) > 1 {
+		return "", errorAt(stmt.operands[1].column(), "too many args supplied")
+	}
+	name, ok := stmt.operands[0].word()
+	if _, known := sectionOrder[strings.ToLower(name)]; !ok || !known {
+		return "", errorAt(stmt.operands[0].column(), "unknown section %q, expected text, data or bss", stmt.operands[0])
+	}
+	return strings.ToLower(name), nil
+}
+
+// placementValue works out the operand of a directive that decides where lines go, like ORG and RESERVE. It can't
+// depend on the address of a label, since labels are only placed once it's known
+func placementValue(stmt *statement, symbolTable symbols) (uint32, error) {
+	if len(stmt.operands) == 0 {
+		return 0, errorAt(stmt.column, "%s needs a value", stmt.mnemonic)
+	}
+	if len(stmt.operands) > 1 {
+		return 0, errorAt(stmt.operands[1].column(), "too many args supplied")
+	}
+	value, usesLabels, err := evaluate(stmt.operands[0], symbolTable, false)
+	if err != nil {
+		return 0, err
+	}
+	if usesLabels {
+		return 0, errorAt(stmt.operands[0].column(), "%s can't depend on the address of a label", stmt.mnemonic)
+	}
+	return value, nil
 }
 
 type directiveTableType map[string]*directive
@@ -241,21 +691,19 @@ type directiveTableType map[string]*directive
 var directiveTable = directiveTableType{
 	"WORD": {
 		mnemonic: "WORD",
-		sizeCalc: func(_ string) uint32 {
+		data:     true,
+		sizeCalc: func(_ *statement, _ symbols) uint32 {
 			// Always one line long
 			return 1
 		},
-		assembleFunc: func(sourceLine string, _ symbols) ([]uint32, error) {
-			spl := strings.Split(sourceLine, " ")
-			col := 1
-			if len(spl) > 2 {
-				col = 2
-			}
-			if len(spl) <= col {
+		assembleFunc: func(stmt *statement, symbolTable symbols) ([]uint32, error) {
+			if len(stmt.operands) == 0 {
 				return nil, fmt.Errorf("not enough arguments to WORD directive")
 			}
-			arg := spl[col]
-			p, err := parseLiteral(arg)
+			if len(stmt.operands) > 1 {
+				return nil, errorAt(stmt.operands[1].column(), "too many args supplied")
+			}
+			p, _, err := evaluate(stmt.operands[0], symbolTable, true)
 			if err != nil {
 				return nil, err
 			}
@@ -264,26 +712,13 @@ var directiveTable = directiveTableType{
 	},
 	"STRING": {
 		mnemonic: "STRING",
-		sizeCalc: func(sourceLine string) uint32 {
-			split := strings.SplitN(sourceLine, " ", 2)
-			if split[0] != "STRING" {
-				split = strings.SplitN(split[1], " ", 2)
-			}
-			if len(split) == 1 {
-				return 1
-			}
-			return uint32(len(split[1]) + 1)
+		data:     true,
+		sizeCalc: func(stmt *statement, _ symbols) uint32 {
+			return uint32(len([]rune(stringText(stmt))) + 1)
 		},
-		assembleFunc: func(sourceLine string, _ symbols) ([]uint32, error) {
-			split := strings.SplitN(sourceLine, " ", 2)
-			if split[0] != "STRING" {
-				split = strings.SplitN(split[1], " ", 2)
-			}
-			if len(split) == 1 {
-				return []uint32{0x00}, nil
-			}
+		assembleFunc: func(stmt *statement, _ symbols) ([]uint32, error) {
 			var ret []uint32
-			for _, ch := range split[1] {
+			for _, ch := range stringText(stmt) {
 				ret = append(ret, uint32(ch))
 			}
 			ret = append(ret, 0x00)
@@ -293,27 +728,102 @@ var directiveTable = directiveTableType{
 	// Loads the address of a symbol into a register
 	"ADDRESS": {
 		mnemonic: "ADDRESS",
-		sizeCalc: func(_ string) uint32 {
+		sizeCalc: func(stmt *statement, symbolTable symbols) uint32 {
+			if len(stmt.operands) > 0 {
+				if p, ok := constantValueOf(stmt.operands[0], symbolTable); ok && p > maxShortImmediate {
+					return 2
+				}
+			}
 			return 1
 		},
-		assembleFunc: func(sourceLine string, symbolTable symbols) ([]uint32, error) {
-			split := strings.Split(sourceLine, " ")
-			symbolIdx := 1
-			if split[0] != "ADDRESS" {
-				symbolIdx = 2
-			}
-			if len(split) <= symbolIdx+1 {
+		assembleFunc: func(stmt *statement, symbolTable symbols) ([]uint32, error) {
+			if len(stmt.operands) < 2 {
 				return nil, fmt.Errorf("ADDRESS directive did not have enough arguments")
 			}
-			symbolName := split[symbolIdx]
-			dest := split[symbolIdx+1]
-			if symbol, ok := symbolTable[symbolName]; ok {
-				instr := fmt.Sprintf("COPY %d %s", symbol.relativeLineNumber, dest)
-				return opcodeTable["COPY"].assemble(instr, symbolTable)
+			if len(stmt.operands) > 2 {
+				return nil, errorAt(stmt.operands[2].column(), "too many args supplied")
+			}
+			address, usesLabels, err := evaluate(stmt.operands[0], symbolTable, true)
+			if err != nil {
+				return nil, err
+			}
+			// Labels are expected to fit in the immediate field, see constantValueOf
+			if usesLabels && address > maxShortImmediate {
+				return nil, errorAt(stmt.operands[0].column(), "address %#x is out of range for a short immediate", address)
+			}
+			instr := &statement{
+				mnemonic: "COPY",
+				operands: []operand{
+					{{tokenType: WORD, text: fmt.Sprintf("%d", address)}},
+					stmt.operands[1],
+				},
+			}
+			return opcodeTable["COPY"].assemble(instr, symbolTable)
+		},
+	},
+	// Places the lines after it at an address, as ORG 0x2000
+	"ORG": {
+		mnemonic: "ORG",
+		origin:   true,
+		sizeCalc: func(_ *statement, _ symbols) uint32 {
+			return 0
+		},
+		assembleFunc: func(stmt *statement, symbolTable symbols) ([]uint32, error) {
+			_, err := placementValue(stmt, symbolTable)
+			return nil, err
+		},
+	},
+	// Switches the section the lines after it go in, as SECTION data
+	"SECTION": {
+		mnemonic: "SECTION",
+		section:  true,
+		sizeCalc: func(_ *statement, _ symbols) uint32 {
+			return 0
+		},
+		assembleFunc: func(stmt *statement, _ symbols) ([]uint32, error) {
+			_, err := sectionName(stmt)
+			return nil, err
+		},
+	},
+	// Sets aside a number of words, which are zero in the text and data sections
+	"RESERVE": {
+		mnemonic:      "RESERVE",
+		data:          true,
+		uninitialised: true,
+		sizeCalc: func(stmt *statement, symbolTable symbols) uint32 {
+			size, err := placementValue(stmt, symbolTable)
+			if err != nil {
+				// The error is reported when the line is assembled
+				return 0
+			}
+			return size
+		},
+		assembleFunc: func(stmt *statement, symbolTable symbols) ([]uint32, error) {
+			size, err := placementValue(stmt, symbolTable)
+			if err != nil {
+				return nil, err
 			}
-			return nil, fmt.Errorf("unrecognised symbol %q", symbolName)
+			return make([]uint32, size), nil
 		},
 	},
+	// Names a constant, as NAME EQU value
+	"EQU": {
+		mnemonic: "EQU",
+		constant: true,
+		sizeCalc: func(_ *statement, _ symbols) uint32 {
+			return 0
+		},
+		assembleFunc: assembleConstant,
+	},
+	// Names a constant, as DEFINE NAME value
+	"DEFINE": {
+		mnemonic: "DEFINE",
+		constant: true,
+		sizeCalc: func(_ *statement, _ symbols) uint32 {
+			return 0
+		},
+		assembleFunc: assembleConstant,
+	},
 }
 
 type register struct {
@@ -340,6 +850,39 @@ var registerTable = registerTableType{
 		mnemonic: "R3",
 		nibble:   0x3,
 	},
+	"R4": {
+		mnemonic: "R4",
+		nibble:   0x4,
+	},
+	"R5": {
+		mnemonic: "R5",
+		nibble:   0x5,
+	},
+	"R6": {
+		mnemonic: "R6",
+		nibble:   0x6,
+	},
+	"R7": {
+		mnemonic: "R7",
+		nibble:   0x7,
+	},
+	"R8": {
+		mnemonic: "R8",
+		nibble:   0x8,
+	},
+	"R9": {
+		mnemonic: "R9",
+		nibble:   0x9,
+	},
+	"R10": {
+		mnemonic: "R10",
+		nibble:   0xA,
+	},
+	// FP is another name for R10
+	"FP": {
+		mnemonic: "FP",
+		nibble:   0xA,
+	},
 	"SP": {
 		mnemonic: "SP",
 		nibble:   0xB,
@@ -369,7 +912,7 @@ func parseLiteral(arg string) (uint32, error) {
 	} else if strings.HasPrefix(arg, "0b") {
 		base = 2
 		stripCount = 2
-	} else if strings.HasPrefix(arg, "0") {
+	} else if strings.HasPrefix(arg, "0") && len(arg) > 1 {
 		base = 8
 		stripCount = 1
 	}
@@ -47,13 +47,31 @@ func Test_opCode_instructionMask(t *testing.T) {
 func Test_directive_size_calcs(t *testing.T) {
 	// string terminates with a null char (0x00)
 	t.Run("test WORD directive", func(t *testing.T) {
-		assert.Equal(t, uint32(1), directiveTable["WORD"].sizeCalc("WORD 0x1234"))
+		assert.Equal(t, uint32(1), directiveTable["WORD"].sizeCalc(mustParse("WORD 0x1234"), symbols{}))
 	})
 	t.Run("test STRING directive no label", func(t *testing.T) {
-		assert.Equal(t, uint32(len("hello, world!")+1), directiveTable["STRING"].sizeCalc("STRING hello, world!"))
+		assert.Equal(t, uint32(len("hello, world!")+1), directiveTable["STRING"].sizeCalc(mustParse("STRING hello, world!"), symbols{}))
 	})
 	t.Run("test STRING directive with label", func(t *testing.T) {
-		assert.Equal(t, uint32(len("hello, world!")+1), directiveTable["STRING"].sizeCalc("ABC STRING hello, world!"))
+		assert.Equal(t, uint32(len("hello, world!")+1), directiveTable["STRING"].sizeCalc(mustParse("ABC STRING hello, world!"), symbols{}))
+	})
+}
+
+func Test_opCode_calculateSize(t *testing.T) {
+	t.Run("registers only", func(t *testing.T) {
+		assert.Equal(t, uint32(1), opcodeTable["ADD"].calculateSize(mustParse("ADD R0 R1"), symbols{}))
+	})
+	t.Run("short immediate", func(t *testing.T) {
+		assert.Equal(t, uint32(1), opcodeTable["COPY"].calculateSize(mustParse("COPY 0xFFFF R0"), symbols{}))
+	})
+	t.Run("long immediate", func(t *testing.T) {
+		assert.Equal(t, uint32(2), opcodeTable["COPY"].calculateSize(mustParse("COPY 0x10000 R0"), symbols{}))
+	})
+	t.Run("long immediate with label", func(t *testing.T) {
+		assert.Equal(t, uint32(2), opcodeTable["COPY"].calculateSize(mustParse("BIG COPY 0x12345 R0"), symbols{}))
+	})
+	t.Run("symbol", func(t *testing.T) {
+		assert.Equal(t, uint32(1), opcodeTable["JMP"].calculateSize(mustParse("JMP LOOP"), symbols{}))
 	})
 }
 
@@ -79,6 +97,16 @@ func Test_opCode_assemble(t *testing.T) {
 			want:    []uint32{0x00000000},
 			wantErr: assert.NoError,
 		},
+		{
+			name:   "IRET",
+			opCode: opcodeTable["IRET"],
+			args: args{
+				sourceLine:  "IRET",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x14000000},
+			wantErr: assert.NoError,
+		},
 		// Add has two inputs, other similar commands should be fine
 		{
 			name:   "ADD no label",
@@ -90,6 +118,16 @@ func Test_opCode_assemble(t *testing.T) {
 			want:    []uint32{0x041F0050},
 			wantErr: assert.NoError,
 		},
+		{
+			name:   "ADD upper registers",
+			opCode: opcodeTable["ADD"],
+			args: args{
+				sourceLine:  "ADD R4 R10",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x044A0000},
+			wantErr: assert.NoError,
+		},
 		{
 			name:   "ADD with label",
 			opCode: opcodeTable["ADD"],
@@ -148,6 +186,282 @@ func Test_opCode_assemble(t *testing.T) {
 			want:    nil,
 			wantErr: assert.Error,
 		},
+		{
+			name:   "AND",
+			opCode: opcodeTable["AND"],
+			args: args{
+				sourceLine:  "AND 0xFF R0",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x15F000FF},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "NOT",
+			opCode: opcodeTable["NOT"],
+			args: args{
+				sourceLine:  "NOT R0 R1",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x18010000},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "SHL with label",
+			opCode: opcodeTable["SHL"],
+			args: args{
+				sourceLine:  "DOUBLE SHL 1 R2",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x19F20001},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "ROR",
+			opCode: opcodeTable["ROR"],
+			args: args{
+				sourceLine:  "ROR R3 R2",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x1D320000},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "SUBS",
+			opCode: opcodeTable["SUBS"],
+			args: args{
+				sourceLine:  "SUBS R0 R1",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x1F010000},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "GTES with label",
+			opCode: opcodeTable["GTES"],
+			args: args{
+				sourceLine:  "CHECK GTES R2 0x10",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x262F0010},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "COPY long immediate",
+			opCode: opcodeTable["COPY"],
+			args: args{
+				sourceLine:  "COPY 0x12345 R0",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x83F00000, 0x00012345},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "GT long immediate in I2",
+			opCode: opcodeTable["GT"],
+			args: args{
+				sourceLine:  "GT R1 0xFFFFFFFF",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x8F1F0000, 0xFFFFFFFF},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "COPY immediate larger than a word",
+			opCode: opcodeTable["COPY"],
+			args: args{
+				sourceLine:  "COPY 0x100000000 R0",
+				symbolTable: symbols{},
+			},
+			want:    nil,
+			wantErr: assert.Error,
+		},
+		{
+			name:   "JMP symbol out of short range",
+			opCode: opcodeTable["JMP"],
+			args: args{
+				sourceLine: "JMP FARAWAY",
+				symbolTable: symbols{
+					"FARAWAY": {
+						symbolType:         REL,
+						label:              "FARAWAY",
+						relativeLineNumber: 0x10000,
+						sourceLine:         "FARAWAY HALT",
+						assemblyLink:       opcodeTable["HALT"],
+					},
+				},
+			},
+			want:    nil,
+			wantErr: assert.Error,
+		},
+		{
+			name:   "LOAD with base register",
+			opCode: opcodeTable["LOAD"],
+			args: args{
+				sourceLine:  "LOAD R0, [R1]",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x27100000},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "LOAD with offset",
+			opCode: opcodeTable["LOAD"],
+			args: args{
+				sourceLine:  "FIELD LOAD R0, [R1 + 4]",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x27100004},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "LOAD with negative offset",
+			opCode: opcodeTable["LOAD"],
+			args: args{
+				sourceLine:  "LOAD R2, [R3-2]",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x2732FFFE},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "LOAD with post-increment",
+			opCode: opcodeTable["LOAD"],
+			args: args{
+				sourceLine:  "LOAD R1, [R0]+",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x29010000},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "LOAD with long offset",
+			opCode: opcodeTable["LOAD"],
+			args: args{
+				sourceLine:  "LOAD R0 [R1 + 0x12345]",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0xA7100000, 0x00012345},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "LOAD with immediate base",
+			opCode: opcodeTable["LOAD"],
+			args: args{
+				sourceLine:  "LOAD R0, [0x10]",
+				symbolTable: symbols{},
+			},
+			want:    nil,
+			wantErr: assert.Error,
+		},
+		{
+			name:   "STORE with offset",
+			opCode: opcodeTable["STORE"],
+			args: args{
+				sourceLine:  "STORE R0, [R1 + 0x10]",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x28010010},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "STORE with post-increment and offset",
+			opCode: opcodeTable["STORE"],
+			args: args{
+				sourceLine:  "STORE R4, [R5 + 1]+",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x2A450001},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "ENTER",
+			opCode: opcodeTable["ENTER"],
+			args: args{
+				sourceLine:  "ENTER 3",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x2BF00003},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "LEAVE",
+			opCode: opcodeTable["LEAVE"],
+			args: args{
+				sourceLine:  "LEAVE",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x2C000000},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "LOAD local from frame pointer",
+			opCode: opcodeTable["LOAD"],
+			args: args{
+				sourceLine:  "LOAD R0, [FP-2]",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x27A0FFFE},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "STORE argument from frame pointer",
+			opCode: opcodeTable["STORE"],
+			args: args{
+				sourceLine:  "STORE R1, [FP + 2]",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x281A0002},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "SYSCALL",
+			opCode: opcodeTable["SYSCALL"],
+			args: args{
+				sourceLine:  "SYSCALL 0x2",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x2DF00002},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "two immediates",
+			opCode: opcodeTable["WRITE"],
+			args: args{
+				sourceLine:  "WRITE 0x41 0xFFE1",
+				symbolTable: symbols{},
+			},
+			want:    nil,
+			wantErr: assert.Error,
+		},
+		{
+			name:   "register then negative immediate",
+			opCode: opcodeTable["LESS"],
+			args: args{
+				sourceLine:  "LESS R0 -1",
+				symbolTable: symbols{},
+			},
+			want:    []uint32{0x8D0F0000, 0xFFFFFFFF},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "register then negative constant",
+			opCode: opcodeTable["ADD"],
+			args: args{
+				sourceLine: "ADD R1 -WIDTH",
+				symbolTable: symbols{
+					"WIDTH": {
+						symbolType:   ABS,
+						label:        "WIDTH",
+						sourceLine:   "WIDTH EQU 8",
+						statement:    mustParse("WIDTH EQU 8"),
+						assemblyLink: directiveTable["EQU"],
+					},
+				},
+			},
+			want:    []uint32{0x841F0000, 0xFFFFFFF8},
+			wantErr: assert.NoError,
+		},
 		// Try JMP for symbol resolution in I1
 		{
 			name:   "JMP with no symbols",
@@ -181,7 +495,7 @@ func Test_opCode_assemble(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			o := tt.opCode
-			got, err := o.assemble(tt.args.sourceLine, tt.args.symbolTable)
+			got, err := o.assemble(mustParse(tt.args.sourceLine), tt.args.symbolTable)
 			if !tt.wantErr(t, err, fmt.Sprintf("assemble(%v)", tt.args.sourceLine)) {
 				return
 			}
@@ -193,7 +507,7 @@ func Test_opCode_assemble(t *testing.T) {
 func Test_directive_assemble(t *testing.T) {
 	type fields struct {
 		mnemonic string
-		sizeCalc func(sourceLine string) uint32
+		sizeCalc func(stmt *statement, symbolTable symbols) uint32
 	}
 	type args struct {
 		sourceLine  string
@@ -264,6 +578,58 @@ func Test_directive_assemble(t *testing.T) {
 			want:    []uint32{0x03F10123},
 			wantErr: assert.NoError,
 		},
+		{
+			name:      "word expression",
+			directive: directiveTable["WORD"],
+			args: args{
+				sourceLine: "WORD BIGDOG + 0x10",
+				symbolTable: symbols{
+					"BIGDOG": {
+						symbolType:         REL,
+						label:              "BIGDOG",
+						relativeLineNumber: 0x123,
+					},
+				},
+			},
+			want:    []uint32{0x133},
+			wantErr: assert.NoError,
+		},
+		{
+			name:      "address expression",
+			directive: directiveTable["ADDRESS"],
+			args: args{
+				sourceLine: "ADDRESS HELLO + 2 R1",
+				symbolTable: symbols{
+					"HELLO": {
+						symbolType:         REL,
+						label:              "HELLO",
+						relativeLineNumber: 0x123,
+					},
+				},
+			},
+			want:    []uint32{0x03F10125},
+			wantErr: assert.NoError,
+		},
+		{
+			name:      "constant",
+			directive: directiveTable["EQU"],
+			args: args{
+				sourceLine:  "WIDTH EQU 40 * 8",
+				symbolTable: symbols{},
+			},
+			want:    nil,
+			wantErr: assert.NoError,
+		},
+		{
+			name:      "constant with unknown symbol",
+			directive: directiveTable["DEFINE"],
+			args: args{
+				sourceLine:  "DEFINE WIDTH COLUMNS * 8",
+				symbolTable: symbols{},
+			},
+			want:    nil,
+			wantErr: assert.Error,
+		},
 		{
 			name:      "address invalid symbol",
 			directive: directiveTable["ADDRESS"],
@@ -286,7 +652,7 @@ func Test_directive_assemble(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			d := tt.directive
-			got, err := d.assemble(tt.args.sourceLine, tt.args.symbolTable)
+			got, err := d.assemble(mustParse(tt.args.sourceLine), tt.args.symbolTable)
 			if !tt.wantErr(t, err, fmt.Sprintf("assemble(%v, %v)", tt.args.sourceLine, tt.args.symbolTable)) {
 				return
 			}
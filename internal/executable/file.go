@@ -5,6 +5,16 @@ import (
 	"io"
 )
 
+// FLAG_BLOCK_PROTECTION is set in a file's flags when each block starts with its protection bits
+const FLAG_BLOCK_PROTECTION uint32 = 1
+
+// Protection bits for a block
+const (
+	BLOCK_READ uint32 = 1 << iota
+	BLOCK_WRITE
+	BLOCK_EXECUTE
+)
+
 // LoadableFile represents a file we can load into memory
 type LoadableFile struct {
 	BlockCount uint32
@@ -15,7 +25,9 @@ type LoadableFile struct {
 type MemoryBlock struct {
 	Address   uint32
 	BlockSize uint32
-	Words     []uint32
+	// Protection is only saved and loaded when the file has FLAG_BLOCK_PROTECTION set
+	Protection uint32
+	Words      []uint32
 }
 
 func (l *LoadableFile) Save(w io.ByteWriter) error {
@@ -24,6 +36,12 @@ func (l *LoadableFile) Save(w io.ByteWriter) error {
 		return err
 	}
 	for bi, b := range l.Blocks {
+		if l.Flags&FLAG_BLOCK_PROTECTION > 0 {
+			err = writeWords(w, b.Protection)
+			if err != nil {
+				return fmt.Errorf("error writing block %d: %v", bi, err)
+			}
+		}
 		err = writeWords(w, b.Address, b.BlockSize)
 		if err != nil {
 			return fmt.Errorf("error writing block %d: %v", bi, err)
@@ -48,7 +66,12 @@ func Load(bs io.ByteReader) (*LoadableFile, error) {
 		return nil, fmt.Errorf("error reading flags: %v", err)
 	}
 
-	blocks, err := loadBlocks(blockCount, bs)
+	var blocks []*MemoryBlock
+	if flags&FLAG_BLOCK_PROTECTION > 0 {
+		blocks, err = loadProtectedBlocks(blockCount, bs)
+	} else {
+		blocks, err = loadBlocks(blockCount, bs)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error loading blocks: %v", err)
 	}
@@ -76,6 +99,26 @@ func loadBlocks(blockCount uint32, bs io.ByteReader) ([]*MemoryBlock, error) {
 	return blocks, nil
 }
 
+// loadProtectedBlocks from a stream where each block starts with its protection bits
+func loadProtectedBlocks(blockCount uint32, bs io.ByteReader) ([]*MemoryBlock, error) {
+	if blockCount == 0 {
+		return nil, nil
+	}
+	blocks := make([]*MemoryBlock, blockCount)
+	for i := 0; i < int(blockCount); i++ {
+		protection, err := nextWord(bs)
+		if err != nil {
+			return nil, fmt.Errorf("error reading protection of block %d: %v", i, err)
+		}
+		blocks[i], err = loadBlock(bs)
+		if err != nil {
+			return nil, fmt.Errorf("error loading block %d: %v", i, err)
+		}
+		blocks[i].Protection = protection
+	}
+	return blocks, nil
+}
+
 // loadBlock from a stream
 func loadBlock(bs io.ByteReader) (*MemoryBlock, error) {
 	address, err := nextWord(bs)
@@ -322,6 +322,40 @@ func TestLoad(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "protected blocks",
+			args: args{
+				bs: bytes.NewReader(uintsToBytes(0x02, FLAG_BLOCK_PROTECTION, BLOCK_READ|BLOCK_EXECUTE, 0x100, 0x01, 0x1234,
+					BLOCK_READ|BLOCK_WRITE, 0x101, 0x01, 0x5678)),
+			},
+			want: &LoadableFile{
+				BlockCount: 2,
+				Flags:      FLAG_BLOCK_PROTECTION,
+				Blocks: []*MemoryBlock{
+					{
+						Address:    0x100,
+						BlockSize:  1,
+						Protection: BLOCK_READ | BLOCK_EXECUTE,
+						Words:      []uint32{0x1234},
+					},
+					{
+						Address:    0x101,
+						BlockSize:  1,
+						Protection: BLOCK_READ | BLOCK_WRITE,
+						Words:      []uint32{0x5678},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "protected block missing protection",
+			args: args{
+				bs: bytes.NewReader(uintsToBytes(0x01, FLAG_BLOCK_PROTECTION)),
+			},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -403,4 +437,30 @@ func TestLoadableFile_Save(t *testing.T) {
 		}
 		assert.Equal(t, uintsToBytes(0x03, 0x0, 0x100, 0x02, 0x1234, 0x5678, 0x200, 0x01, 0x1234, 0x300, 0x03, 0x1234, 0x5678, 0x90), buf.Bytes())
 	})
+	t.Run("successful save protected blocks", func(t *testing.T) {
+		buf := bytes.NewBuffer([]byte{})
+		file := &LoadableFile{
+			BlockCount: 2,
+			Flags:      FLAG_BLOCK_PROTECTION,
+			Blocks: []*MemoryBlock{
+				{
+					Address:    0x100,
+					BlockSize:  1,
+					Protection: BLOCK_READ | BLOCK_EXECUTE,
+					Words:      []uint32{0x1234},
+				},
+				{
+					Address:    0x101,
+					BlockSize:  1,
+					Protection: BLOCK_READ | BLOCK_WRITE,
+					Words:      []uint32{0x5678},
+				},
+			},
+		}
+		err := file.Save(buf)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, uintsToBytes(0x02, FLAG_BLOCK_PROTECTION, 0x05, 0x100, 0x01, 0x1234, 0x03, 0x101, 0x01, 0x5678), buf.Bytes())
+	})
 }
@@ -1,33 +1,227 @@
 package machine
 
-import "fmt"
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
 
+// Addresses are looked up through a page table of busPages pages, each 1<<busPageBits words long. Addresses
+// past the table, or in a page shared by more than one device, are found by searching the sorted device ranges
+const (
+	busPageBits = 8
+	busPages    = 1 << 12
+)
+
+// Page table entries that don't point at a single device. Other entries are one more than the index of the
+// device's mapping, so an empty table maps nothing
+const (
+	pageUnmapped = int32(0)
+	pageShared   = int32(-1)
+)
+
+// Bus connects the CPU to the devices mapped into its address space. Devices may not share addresses. Attach and
+// Detach must not be called while another goroutine is using the bus
 type Bus struct {
 	devices []BusDevice
+	// mappings holds each device's range, sorted by address, so lookups don't need to call MemoryRange
+	mappings []DeviceMapping
+	pages    [busPages]int32
+	tickers  []TickingDevice
+	// RAM is by far the busiest device, so it skips the lookup
+	ram *Memory
 }
 
-func (b *Bus) Read(address uint32) (uint32, error) {
-	for _, d := range b.devices {
-		memRange := d.MemoryRange()
-		if memRange.Start <= address && address <= memRange.End {
-			return d.Read(address)
+// DeviceMapping is one entry in the bus's memory map
+type DeviceMapping struct {
+	Start  uint32
+	End    uint32
+	Device BusDevice
+}
+
+func (m DeviceMapping) String() string {
+	return fmt.Sprintf("0x%05X-0x%05X %s", m.Start, m.End, deviceName(m.Device))
+}
+
+// deviceName gives the type name of a device, without its package or pointer
+func deviceName(d BusDevice) string {
+	t := reflect.TypeOf(d)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// lookup finds the device mapped at address, or nil if there isn't one
+func (b *Bus) lookup(address uint32) BusDevice {
+	if page := address >> busPageBits; page < busPages {
+		switch idx := b.pages[page]; idx {
+		case pageUnmapped:
+			return nil
+		case pageShared:
+		default:
+			return b.mappings[idx-1].Device
+		}
+	}
+	// Find the first mapping that ends at or after address
+	low, high := 0, len(b.mappings)
+	for low < high {
+		mid := int(uint(low+high) >> 1)
+		if b.mappings[mid].End < address {
+			low = mid + 1
+		} else {
+			high = mid
 		}
 	}
+	if low < len(b.mappings) && b.mappings[low].Start <= address {
+		return b.mappings[low].Device
+	}
+	return nil
+}
+
+func (b *Bus) Read(address uint32) (uint32, error) {
+	if b.ram != nil && address <= maxMemoryAddress {
+		return b.ram.Read(address)
+	}
+	if d := b.lookup(address); d != nil {
+		return d.Read(address)
+	}
 	return 0, fmt.Errorf("bus read: unmapped address %x", address)
 }
 
 func (b *Bus) Write(address, value uint32) error {
+	if b.ram != nil && address <= maxMemoryAddress {
+		return b.ram.Write(address, value)
+	}
+	if d := b.lookup(address); d != nil {
+		return d.Write(address, value)
+	}
+	return fmt.Errorf("bus write: unmapped address %x", address)
+}
+
+// Fetch reads an instruction word, failing if the device holding it doesn't allow it to be executed
+func (b *Bus) Fetch(address uint32) (uint32, error) {
+	if b.ram != nil && address <= maxMemoryAddress {
+		if !b.ram.Executable(address) {
+			return 0, fmt.Errorf("bus fetch: address %x is not executable", address)
+		}
+		return b.ram.Read(address)
+	}
+	d := b.lookup(address)
+	if d == nil {
+		return 0, fmt.Errorf("bus fetch: unmapped address %x", address)
+	}
+	if p, ok := d.(ProtectedDevice); ok && !p.Executable(address) {
+		return 0, fmt.Errorf("bus fetch: address %x is not executable", address)
+	}
+	return d.Read(address)
+}
+
+// Tick lets every TickingDevice on the bus do its work for this tick
+func (b *Bus) Tick() {
+	for _, t := range b.tickers {
+		t.Tick()
+	}
+}
+
+// Attach adds a device to the bus. It fails if the device's range is invalid or overlaps a device already attached
+func (b *Bus) Attach(d BusDevice) error {
+	memRange := d.MemoryRange()
+	if memRange.Start > memRange.End {
+		return fmt.Errorf("%s has an invalid range %x-%x", deviceName(d), memRange.Start, memRange.End)
+	}
+	for _, existing := range b.devices {
+		if existing == d {
+			return fmt.Errorf("%s is already attached", deviceName(d))
+		}
+		other := existing.MemoryRange()
+		if memRange.Start <= other.End && other.Start <= memRange.End {
+			return fmt.Errorf("%s at %x-%x overlaps %s at %x-%x", deviceName(d), memRange.Start, memRange.End,
+				deviceName(existing), other.Start, other.End)
+		}
+	}
+	b.devices = append(b.devices, d)
+	b.remap()
+	if m, ok := d.(BusMaster); ok {
+		m.ConnectBus(b)
+	}
+	return nil
+}
+
+// Detach removes a device from the bus, leaving its addresses unmapped. A BusMaster is disconnected from the bus
+func (b *Bus) Detach(d BusDevice) error {
+	for i, existing := range b.devices {
+		if existing == d {
+			b.devices = append(b.devices[:i], b.devices[i+1:]...)
+			b.remap()
+			if m, ok := d.(BusMaster); ok {
+				m.ConnectBus(nil)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not attached", deviceName(d))
+}
+
+// Map lists the devices on the bus in address order
+func (b *Bus) Map() []DeviceMapping {
+	ret := make([]DeviceMapping, len(b.mappings))
+	copy(ret, b.mappings)
+	return ret
+}
+
+// remap rebuilds the lookup tables after the attached devices change
+func (b *Bus) remap() {
+	b.mappings = b.mappings[:0]
+	b.tickers = b.tickers[:0]
+	b.ram = nil
 	for _, d := range b.devices {
 		memRange := d.MemoryRange()
-		if memRange.Start <= address && address <= memRange.End {
-			return d.Write(address, value)
+		b.mappings = append(b.mappings, DeviceMapping{
+			Start:  memRange.Start,
+			End:    memRange.End,
+			Device: d,
+		})
+		if t, ok := d.(TickingDevice); ok {
+			b.tickers = append(b.tickers, t)
+		}
+		if m, ok := d.(*Memory); ok {
+			b.ram = m
+		}
+	}
+	sort.Slice(b.mappings, func(i, j int) bool {
+		return b.mappings[i].Start < b.mappings[j].Start
+	})
+	for i := range b.pages {
+		b.pages[i] = pageUnmapped
+	}
+	for idx, m := range b.mappings {
+		if m.Start>>busPageBits >= busPages {
+			continue
+		}
+		last := m.End >> busPageBits
+		if last >= busPages {
+			last = busPages - 1
+		}
+		for page := m.Start >> busPageBits; page <= last; page++ {
+			pageStart := page << busPageBits
+			pageEnd := pageStart + 1<<busPageBits - 1
+			if b.pages[page] == pageUnmapped && m.Start <= pageStart && pageEnd <= m.End {
+				b.pages[page] = int32(idx + 1)
+			} else {
+				b.pages[page] = pageShared
+			}
 		}
 	}
-	return fmt.Errorf("bus write: unmapped address %x", address)
 }
 
-func NewBus(devices ...BusDevice) *Bus {
-	return &Bus{
-		devices: devices,
+// NewBus creates a bus with devices attached, returning an error if any of their ranges overlap
+func NewBus(devices ...BusDevice) (*Bus, error) {
+	b := &Bus{}
+	for _, d := range devices {
+		if err := b.Attach(d); err != nil {
+			return nil, fmt.Errorf("could not create bus: %v", err)
+		}
 	}
+	return b, nil
 }
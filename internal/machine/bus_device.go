@@ -7,10 +7,32 @@ type MemoryRange struct {
 
 // BusDevice is an interface for devices we attach to the bus
 type BusDevice interface {
-	// MemoryRange gives the memory range of the device
+	// MemoryRange gives the memory range of the device. The bus reads it once when the device is attached, so it
+	// must not change afterwards
 	MemoryRange() *MemoryRange
 	// Read takes an address and returns the Value at address
 	Read(address uint32) (uint32, error)
 	// Write writes Value to address
 	Write(address, value uint32) error
 }
+
+// TickingDevice is a bus device that does some work every time the CPU ticks
+type TickingDevice interface {
+	BusDevice
+	// Tick is called once after every CPU tick
+	Tick()
+}
+
+// BusMaster is a bus device that reads and writes other devices on the bus itself
+type BusMaster interface {
+	BusDevice
+	// ConnectBus is called with the bus the device has been attached to, and with nil when it is detached
+	ConnectBus(b *Bus)
+}
+
+// ProtectedDevice is a bus device that controls which of its addresses may be run as code
+type ProtectedDevice interface {
+	BusDevice
+	// Executable reports whether the word at address may be fetched as an instruction
+	Executable(address uint32) bool
+}
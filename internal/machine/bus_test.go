@@ -1,14 +1,25 @@
 package machine
 
 import (
+	"github.com/ThreeToes/blogvm/internal/executable"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
+// newBus creates a bus for a test, failing the test if the devices overlap
+func newBus(t testing.TB, devices ...BusDevice) *Bus {
+	t.Helper()
+	bus, err := NewBus(devices...)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	return bus
+}
+
 func TestBus_Write(t *testing.T) {
 	t.Run("device written correctly", func(t *testing.T) {
 		m := NewMemory()
-		bus := NewBus(m)
+		bus := newBus(t, m)
 		err := bus.Write(0x100, 0xCCCC)
 		if !assert.NoError(t, err) {
 			t.FailNow()
@@ -16,7 +27,7 @@ func TestBus_Write(t *testing.T) {
 		assert.Equal(t, uint32(0xCCCC), m.mem[0x100])
 	})
 	t.Run("no mapped device", func(t *testing.T) {
-		bus := NewBus()
+		bus := newBus(t)
 		err := bus.Write(0x100, 0xCCCC)
 		if !assert.Error(t, err) {
 			t.FailNow()
@@ -28,7 +39,7 @@ func TestBus_Read(t *testing.T) {
 	t.Run("device read correctly", func(t *testing.T) {
 		m := NewMemory()
 		m.mem[0x100] = 0xFFFF
-		bus := NewBus(m)
+		bus := newBus(t, m)
 		got, err := bus.Read(0x100)
 		if !assert.NoError(t, err) {
 			t.FailNow()
@@ -36,7 +47,7 @@ func TestBus_Read(t *testing.T) {
 		assert.Equal(t, uint32(0xFFFF), got)
 	})
 	t.Run("no mapped device", func(t *testing.T) {
-		bus := NewBus()
+		bus := newBus(t)
 		got, err := bus.Read(0x100)
 		if !assert.Error(t, err) {
 			t.FailNow()
@@ -44,3 +55,254 @@ func TestBus_Read(t *testing.T) {
 		assert.Equal(t, uint32(0), got)
 	})
 }
+
+func TestBus_Attach(t *testing.T) {
+	t.Run("overlapping devices", func(t *testing.T) {
+		bus := newBus(t, NewMemory())
+		rom, _ := NewROM(&executable.MemoryBlock{Address: 0xFFD0, BlockSize: 1, Words: []uint32{0x01}})
+		assert.Error(t, bus.Attach(rom))
+		assert.Len(t, bus.Map(), 1)
+	})
+	t.Run("touching devices", func(t *testing.T) {
+		bus := newBus(t, NewMemory())
+		assert.NoError(t, bus.Attach(NewTimer(nil)))
+		term, _ := NewBufferedTerminal("")
+		assert.NoError(t, bus.Attach(term))
+	})
+	t.Run("attached twice", func(t *testing.T) {
+		mem := NewMemory()
+		bus := newBus(t, mem)
+		assert.Error(t, bus.Attach(mem))
+	})
+	t.Run("overlap at construction", func(t *testing.T) {
+		bus, err := NewBus(NewMemory(), NewMemory())
+		assert.Error(t, err)
+		assert.Nil(t, bus)
+	})
+	t.Run("connects bus masters", func(t *testing.T) {
+		bus := newBus(t, NewMemory())
+		disk := NewMemoryDisk(1, nil)
+		assert.NoError(t, bus.Attach(disk))
+		assert.NoError(t, bus.Write(DISK_COMMAND, DISK_READ))
+		status, _ := bus.Read(DISK_STATUS)
+		assert.Equal(t, DISK_DONE, status)
+	})
+}
+
+func TestBus_Detach(t *testing.T) {
+	timer := NewTimer(nil)
+	bus := newBus(t, NewMemory(), timer)
+	assert.NoError(t, bus.Detach(timer))
+	_, err := bus.Read(TIMER_COUNTER)
+	assert.Error(t, err)
+	assert.Error(t, bus.Detach(timer))
+	assert.NoError(t, bus.Attach(timer))
+	_, err = bus.Read(TIMER_COUNTER)
+	assert.NoError(t, err)
+}
+
+func TestBus_DetachBusMaster(t *testing.T) {
+	dma := NewDMAController(nil)
+	bus := newBus(t, NewMemory(), dma)
+	assert.Equal(t, bus, dma.bus)
+	assert.NoError(t, bus.Detach(dma))
+	assert.Nil(t, dma.bus)
+}
+
+func TestBus_Map(t *testing.T) {
+	mem := NewMemory()
+	ic := NewInterruptController()
+	timer := NewTimer(ic)
+	bus := newBus(t, ic, mem, timer)
+	assert.Equal(t, []DeviceMapping{
+		{Start: 0x0000, End: 0xFFE0, Device: mem},
+		{Start: 0xFFF4, End: 0xFFF7, Device: timer},
+		{Start: 0xFFF8, End: 0xFFFF, Device: ic},
+	}, bus.Map())
+	assert.Equal(t, "0x00000-0x0FFE0 Memory", bus.Map()[0].String())
+	assert.Equal(t, "0x0FFF8-0x0FFFF InterruptController", bus.Map()[2].String())
+}
+
+func TestBus_lookup(t *testing.T) {
+	mem := NewMemory()
+	term, _ := NewBufferedTerminal("")
+	timer := NewTimer(nil)
+	rom, _ := NewROM(&executable.MemoryBlock{Address: 0x200000, BlockSize: 2, Words: []uint32{0x01, 0x02}})
+	fb := NewFramebuffer()
+	bus := newBus(t, mem, term, timer, rom, fb)
+	tests := []struct {
+		address uint32
+		want    BusDevice
+	}{
+		{address: 0x0000, want: mem},
+		{address: 0xFF00, want: mem},
+		{address: 0xFFE0, want: mem},
+		{address: 0xFFE1, want: term},
+		{address: 0xFFE7, want: term},
+		{address: 0xFFE8, want: nil},
+		{address: 0xFFF4, want: timer},
+		{address: 0xFFF8, want: nil},
+		{address: 0x10000, want: fb},
+		{address: FRAMEBUFFER_CONTROL, want: fb},
+		{address: FRAMEBUFFER_CONTROL + 1, want: nil},
+		{address: 0x1FFFFF, want: nil},
+		{address: 0x200001, want: rom},
+		{address: 0x200002, want: nil},
+		{address: 0xFFFFFFFF, want: nil},
+	}
+	for _, tt := range tests {
+		got := bus.lookup(tt.address)
+		assert.Equal(t, tt.want, got, "lookup(%x)", tt.address)
+	}
+	t.Run("empty bus", func(t *testing.T) {
+		bus := &Bus{}
+		_, err := bus.Read(0x100)
+		assert.Error(t, err)
+	})
+	t.Run("detach remaps", func(t *testing.T) {
+		assert.NoError(t, bus.Detach(term))
+		assert.Nil(t, bus.lookup(0xFFE1))
+		assert.Equal(t, timer, bus.lookup(0xFFF4))
+	})
+	t.Run("no allocations", func(t *testing.T) {
+		allocs := testing.AllocsPerRun(100, func() {
+			bus.Read(0x100)
+			bus.Read(TIMER_COUNTER)
+			bus.Read(0x200001)
+			bus.Fetch(0x100)
+			bus.Write(0x100, 0x01)
+		})
+		assert.Equal(t, float64(0), allocs)
+	})
+}
+
+// linearBus dispatches the way the bus used to, asking every device for its range on every access
+type linearBus struct {
+	devices []BusDevice
+}
+
+func (b *linearBus) Read(address uint32) (uint32, error) {
+	for _, d := range b.devices {
+		memRange := d.MemoryRange()
+		if memRange.Start <= address && address <= memRange.End {
+			return d.Read(address)
+		}
+	}
+	return 0, nil
+}
+
+func (b *linearBus) Write(address, value uint32) error {
+	for _, d := range b.devices {
+		memRange := d.MemoryRange()
+		if memRange.Start <= address && address <= memRange.End {
+			return d.Write(address, value)
+		}
+	}
+	return nil
+}
+
+// MemoryRange covers everything so that a linearBus can be attached to a Bus and driven by the CPU
+func (b *linearBus) MemoryRange() *MemoryRange {
+	return &MemoryRange{Start: 0, End: 0xFFFFFFFF}
+}
+
+func (b *linearBus) Tick() {
+	for _, d := range b.devices {
+		if t, ok := d.(TickingDevice); ok {
+			t.Tick()
+		}
+	}
+}
+
+func benchmarkDevices() []BusDevice {
+	ic := NewInterruptController()
+	term, _ := NewBufferedTerminal("")
+	return []BusDevice{NewMemory(), term, NewMemoryDisk(1, ic), NewDMAController(ic), NewTimer(ic), ic, NewFramebuffer()}
+}
+
+func BenchmarkBus_ReadRAM(b *testing.B) {
+	bus := newBus(b, benchmarkDevices()...)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bus.Read(uint32(i) & 0x7FFF)
+	}
+}
+
+func BenchmarkBus_ReadDevice(b *testing.B) {
+	bus := newBus(b, benchmarkDevices()...)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bus.Read(INTERRUPT_MASK)
+	}
+}
+
+func BenchmarkBus_ReadFramebuffer(b *testing.B) {
+	bus := newBus(b, benchmarkDevices()...)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bus.Read(FRAMEBUFFER + uint32(i)&0x7FFF)
+	}
+}
+
+func BenchmarkLinearScan_ReadRAM(b *testing.B) {
+	bus := &linearBus{devices: benchmarkDevices()}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bus.Read(uint32(i) & 0x7FFF)
+	}
+}
+
+func BenchmarkLinearScan_ReadDevice(b *testing.B) {
+	bus := &linearBus{devices: benchmarkDevices()}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bus.Read(INTERRUPT_MASK)
+	}
+}
+
+func BenchmarkLinearScan_ReadFramebuffer(b *testing.B) {
+	bus := &linearBus{devices: benchmarkDevices()}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bus.Read(FRAMEBUFFER + uint32(i)&0x7FFF)
+	}
+}
+
+// loadCountdown loads a loop that counts R0 down from 0xFFFF, starting over each time it reaches zero
+func loadCountdown(b *testing.B, bus *Bus) {
+	// COPY 0xFFFF R0, ADD 0xFFFFFFFF R0, EQ 0x00 R0, JMP 0x100, JMP 0x101
+	program := []uint32{0x03F0FFFF, 0x84F00000, 0xFFFFFFFF, 0x11F00000, 0x0CF00100, 0x0CF00101}
+	for i, word := range program {
+		if err := bus.Write(0x100+uint32(i), word); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func runCountdown(b *testing.B, bus *Bus) {
+	loadCountdown(b, bus)
+	cpu := NewCPU(NewRegisterBank(), bus)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := cpu.Tick(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBus_RunProgram(b *testing.B) {
+	runCountdown(b, newBus(b, benchmarkDevices()...))
+}
+
+// BenchmarkLinearScan_RunProgram runs the same program with every access going through a linearBus. The bus in
+// front of it only ever has one device to find, so nearly all of the dispatch cost is the linear scan
+func BenchmarkLinearScan_RunProgram(b *testing.B) {
+	runCountdown(b, newBus(b, &linearBus{devices: benchmarkDevices()}))
+}
@@ -1,6 +1,11 @@
 package machine
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+)
 
 const (
 	HALT = iota
@@ -23,11 +28,53 @@ const (
 	EQ
 	CALL
 	RETURN
+	IRET
+	AND
+	OR
+	XOR
+	NOT
+	SHL
+	SHR
+	SAR
+	ROL
+	ROR
+	ADDS
+	SUBS
+	MULS
+	DIVS
+	MOD
+	LESSS
+	LTES
+	GTS
+	GTES
+	LOAD
+	STORE
+	LOADINC
+	STOREINC
+	ENTER
+	LEAVE
+	SYSCALL
 )
 
+// EXTENDED is set in the opcode byte of instructions whose immediate data is held in the following word
+const EXTENDED = 0x80
+
+// errStackOverflow is returned by stackPush when the stack has reached its limit
+var errStackOverflow = errors.New("stack overflow")
+
+// SyscallHandler services a SYSCALL from a guest program. Handlers take their arguments from and leave their
+// results in the registers, and can reach memory and devices through the bus
+type SyscallHandler func(*RegisterBank, *Bus) error
+
 type CPU struct {
-	registers *RegisterBank
-	bus       *Bus
+	registers  *RegisterBank
+	bus        *Bus
+	interrupts *InterruptController
+	syscalls   map[uint32]SyscallHandler
+	// pendingFault is the cause of a fault raised by the current instruction
+	pendingFault uint32
+	// faultPC is the address of the current instruction
+	faultPC uint32
 }
 
 func (c *CPU) halt(_, _ *Register) {
@@ -41,11 +88,7 @@ func (c *CPU) halt(_, _ *Register) {
 func (c *CPU) read(i1, i2 *Register) {
 	val, err := c.bus.Read(i1.Value)
 	if err != nil {
-		sr, err := c.registers.GetRegister(SR)
-		if err != nil {
-			panic(err)
-		}
-		sr.Value = sr.Value | STATUS_MEMORY_ERROR
+		c.fault(FAULT_MEMORY)
 		return
 	}
 	i2.Value = val
@@ -54,11 +97,7 @@ func (c *CPU) read(i1, i2 *Register) {
 func (c *CPU) write(i1, i2 *Register) {
 	err := c.bus.Write(i2.Value, i1.Value)
 	if err != nil {
-		sr, err := c.registers.GetRegister(SR)
-		if err != nil {
-			panic(err)
-		}
-		sr.Value = sr.Value | STATUS_MEMORY_ERROR
+		c.fault(FAULT_MEMORY)
 	}
 }
 
@@ -91,7 +130,7 @@ func (c *CPU) sub(i1, i2 *Register) {
 			panic(err)
 		}
 		sr.Value = sr.Value | STATUS_UNDERFLOW
-		diff = diff + 0xFFFFFFFF
+		diff = diff + 0x100000000
 	}
 	i2.Value = uint32(diff & 0xFFFFFFFF)
 }
@@ -118,12 +157,7 @@ func (c *CPU) div(i1, i2 *Register) {
 	i2Val := i2.Value
 
 	if i2Val == 0 {
-		sr, err := c.registers.GetRegister(SR)
-		// We'll panic here because if the status register doesn't work then our machine may as well crash
-		if err != nil {
-			panic(err)
-		}
-		sr.Value = sr.Value | STATUS_DIVIDE_BY_ZERO
+		c.fault(FAULT_DIVIDE_BY_ZERO)
 		return
 	}
 
@@ -150,45 +184,24 @@ func (c *CPU) set(i1, i2 *Register) {
 	if i2.Value > 0 {
 		sr.Value = sr.Value | bit
 	} else {
-		sr.Value = sr.Value ^ bit
+		sr.Value = sr.Value &^ bit
 	}
 }
 
 func (c *CPU) push(i1, _ *Register) {
-	sp, err := c.registers.GetRegister(SP)
+	err := c.stackPush(i1.Value)
 	if err != nil {
-		panic(err)
-	}
-
-	sp.Value--
-	err = c.bus.Write(sp.Value, i1.Value)
-	if err != nil {
-		sr, err := c.registers.GetRegister(SR)
-		if err != nil {
-			panic(err)
-		}
-		sr.Value = sr.Value | STATUS_MEMORY_ERROR
-		return
+		c.stackFault(err)
 	}
 }
 
 func (c *CPU) pop(_, i2 *Register) {
-	sp, err := c.registers.GetRegister(SP)
-	if err != nil {
-		panic(err)
-	}
-
-	v, err := c.bus.Read(sp.Value)
+	v, err := c.stackPop()
 	if err != nil {
-		sr, err := c.registers.GetRegister(SR)
-		if err != nil {
-			panic(err)
-		}
-		sr.Value = sr.Value | STATUS_MEMORY_ERROR
+		c.fault(FAULT_MEMORY)
 		return
 	}
 	i2.Value = v
-	sp.Value++
 }
 
 func (c *CPU) jmp(i1, _ *Register) {
@@ -202,107 +215,490 @@ func (c *CPU) jmp(i1, _ *Register) {
 
 func (c *CPU) less(i1, i2 *Register) {
 	if !(i1.Value < i2.Value) {
-		pc, err := c.registers.GetRegister(PC)
-		if err != nil {
-			panic(err)
-		}
-		pc.Value++
+		c.skip()
 	}
 }
 
 func (c *CPU) lte(i1, i2 *Register) {
 	if !(i1.Value <= i2.Value) {
-		pc, err := c.registers.GetRegister(PC)
-		if err != nil {
-			panic(err)
-		}
-		pc.Value++
+		c.skip()
 	}
 }
 
 func (c *CPU) gt(i1, i2 *Register) {
 	if !(i1.Value > i2.Value) {
-		pc, err := c.registers.GetRegister(PC)
-		if err != nil {
-			panic(err)
-		}
-		pc.Value++
+		c.skip()
 	}
 }
 
 func (c *CPU) gte(i1, i2 *Register) {
 	if !(i1.Value >= i2.Value) {
-		pc, err := c.registers.GetRegister(PC)
-		if err != nil {
-			panic(err)
-		}
-		pc.Value++
+		c.skip()
 	}
 }
 
 func (c *CPU) eq(i1, i2 *Register) {
 	if !(i1.Value == i2.Value) {
-		pc, err := c.registers.GetRegister(PC)
-		if err != nil {
-			panic(err)
-		}
-		pc.Value++
+		c.skip()
 	}
 }
 
 func (c *CPU) call(i1, _ *Register) {
-	sp, err := c.registers.GetRegister(SP)
+	pc, err := c.registers.GetRegister(PC)
 	if err != nil {
 		panic(err)
 	}
+
+	err = c.stackPush(pc.Value)
+	if err != nil {
+		c.stackFault(err)
+		return
+	}
+
+	pc.Value = i1.Value
+}
+
+func (c *CPU) ret(_, _ *Register) {
+	val, err := c.stackPop()
+	if err != nil {
+		c.fault(FAULT_MEMORY)
+		return
+	}
+
 	pc, err := c.registers.GetRegister(PC)
 	if err != nil {
 		panic(err)
 	}
+	pc.Value = val
+}
 
-	sp.Value--
-	err = c.bus.Write(sp.Value, pc.Value)
+func (c *CPU) iret(_, _ *Register) {
+	sr, err := c.registers.GetRegister(SR)
 	if err != nil {
-		sr, err := c.registers.GetRegister(SR)
-		if err != nil {
-			panic(err)
-		}
-		sr.Value = sr.Value | STATUS_MEMORY_ERROR
+		panic(err)
+	}
+	pc, err := c.registers.GetRegister(PC)
+	if err != nil {
+		panic(err)
+	}
+
+	savedSR, err := c.stackPop()
+	if err != nil {
+		c.fault(FAULT_MEMORY)
 		return
 	}
+	savedPC, err := c.stackPop()
+	if err != nil {
+		c.fault(FAULT_MEMORY)
+		return
+	}
+	sr.Value = savedSR
+	pc.Value = savedPC
+}
 
-	pc.Value = i1.Value
+func (c *CPU) and(i1, i2 *Register) {
+	i2.Value = i1.Value & i2.Value
 }
 
-func (c *CPU) ret(_, _ *Register) {
+func (c *CPU) or(i1, i2 *Register) {
+	i2.Value = i1.Value | i2.Value
+}
+
+func (c *CPU) xor(i1, i2 *Register) {
+	i2.Value = i1.Value ^ i2.Value
+}
+
+func (c *CPU) not(i1, i2 *Register) {
+	i2.Value = ^i1.Value
+}
+
+// The shift and rotate instructions move I2 by I1 bits, leaving the last bit shifted out in STATUS_CARRY
+
+func (c *CPU) shl(i1, i2 *Register) {
+	n := i1.Value
+	switch {
+	case n == 0:
+		c.setFlag(STATUS_CARRY, false)
+	case n <= 32:
+		c.setFlag(STATUS_CARRY, i2.Value&(1<<(32-n)) > 0)
+		i2.Value = uint32(uint64(i2.Value) << n)
+	default:
+		c.setFlag(STATUS_CARRY, false)
+		i2.Value = 0
+	}
+}
+
+func (c *CPU) shr(i1, i2 *Register) {
+	n := i1.Value
+	switch {
+	case n == 0:
+		c.setFlag(STATUS_CARRY, false)
+	case n <= 32:
+		c.setFlag(STATUS_CARRY, i2.Value&(1<<(n-1)) > 0)
+		i2.Value = uint32(uint64(i2.Value) >> n)
+	default:
+		c.setFlag(STATUS_CARRY, false)
+		i2.Value = 0
+	}
+}
+
+func (c *CPU) sar(i1, i2 *Register) {
+	n := i1.Value
+	switch {
+	case n == 0:
+		c.setFlag(STATUS_CARRY, false)
+	case n < 32:
+		c.setFlag(STATUS_CARRY, i2.Value&(1<<(n-1)) > 0)
+		i2.Value = uint32(int32(i2.Value) >> n)
+	default:
+		// Everything has been shifted out, leaving only copies of the sign bit
+		c.setFlag(STATUS_CARRY, i2.Value&0x80000000 > 0)
+		i2.Value = uint32(int32(i2.Value) >> 31)
+	}
+}
+
+func (c *CPU) rol(i1, i2 *Register) {
+	n := int(i1.Value % 32)
+	i2.Value = bits.RotateLeft32(i2.Value, n)
+	c.setFlag(STATUS_CARRY, i1.Value > 0 && i2.Value&0x1 > 0)
+}
+
+func (c *CPU) ror(i1, i2 *Register) {
+	n := int(i1.Value % 32)
+	i2.Value = bits.RotateLeft32(i2.Value, -n)
+	c.setFlag(STATUS_CARRY, i1.Value > 0 && i2.Value&0x80000000 > 0)
+}
+
+// The signed instructions treat their inputs as two's complement and update STATUS_ZERO, STATUS_NEGATIVE and
+// STATUS_SIGNED_OVERFLOW from the result. ADDS and SUBS also leave the unsigned carry or borrow in STATUS_CARRY
+
+func (c *CPU) adds(i1, i2 *Register) {
+	sum := int64(int32(i1.Value)) + int64(int32(i2.Value))
+	c.setFlag(STATUS_CARRY, uint64(i1.Value)+uint64(i2.Value) > 0xFFFFFFFF)
+	c.setSignedFlags(uint32(sum), sum > math.MaxInt32 || sum < math.MinInt32)
+	i2.Value = uint32(sum)
+}
+
+func (c *CPU) subs(i1, i2 *Register) {
+	diff := int64(int32(i1.Value)) - int64(int32(i2.Value))
+	c.setFlag(STATUS_CARRY, i1.Value < i2.Value)
+	c.setSignedFlags(uint32(diff), diff > math.MaxInt32 || diff < math.MinInt32)
+	i2.Value = uint32(diff)
+}
+
+func (c *CPU) muls(i1, i2 *Register) {
+	product := int64(int32(i1.Value)) * int64(int32(i2.Value))
+	c.setSignedFlags(uint32(product), product > math.MaxInt32 || product < math.MinInt32)
+	i2.Value = uint32(product)
+}
+
+func (c *CPU) divs(i1, i2 *Register) {
+	dividend := int32(i1.Value)
+	divisor := int32(i2.Value)
+	if divisor == 0 {
+		c.fault(FAULT_DIVIDE_BY_ZERO)
+		return
+	}
+	// The only overflowing case, MinInt32 / -1, wraps back to MinInt32
+	quotient := dividend / divisor
+	c.setSignedFlags(uint32(quotient), dividend == math.MinInt32 && divisor == -1)
+	i2.Value = uint32(quotient)
+}
+
+func (c *CPU) mod(i1, i2 *Register) {
+	dividend := int32(i1.Value)
+	divisor := int32(i2.Value)
+	if divisor == 0 {
+		c.fault(FAULT_DIVIDE_BY_ZERO)
+		return
+	}
+	// The remainder takes the sign of the dividend
+	remainder := dividend % divisor
+	c.setSignedFlags(uint32(remainder), false)
+	i2.Value = uint32(remainder)
+}
+
+func (c *CPU) lesss(i1, i2 *Register) {
+	if !(int32(i1.Value) < int32(i2.Value)) {
+		c.skip()
+	}
+}
+
+func (c *CPU) ltes(i1, i2 *Register) {
+	if !(int32(i1.Value) <= int32(i2.Value)) {
+		c.skip()
+	}
+}
+
+func (c *CPU) gts(i1, i2 *Register) {
+	if !(int32(i1.Value) > int32(i2.Value)) {
+		c.skip()
+	}
+}
+
+func (c *CPU) gtes(i1, i2 *Register) {
+	if !(int32(i1.Value) >= int32(i2.Value)) {
+		c.skip()
+	}
+}
+
+// skip moves PC past the next instruction, including the immediate word of an extended instruction
+func (c *CPU) skip() {
+	pc, err := c.registers.GetRegister(PC)
+	if err != nil {
+		panic(err)
+	}
+	next, err := c.bus.Read(pc.Value)
+	if err != nil {
+		c.fault(FAULT_MEMORY)
+		return
+	}
+	if (next>>24)&EXTENDED > 0 {
+		pc.Value += 2
+	} else {
+		pc.Value++
+	}
+}
+
+// setFlag sets or clears a single flag in SR
+func (c *CPU) setFlag(flag uint32, on bool) {
+	sr, err := c.registers.GetRegister(SR)
+	if err != nil {
+		panic(err)
+	}
+	if on {
+		sr.Value = sr.Value | flag
+	} else {
+		sr.Value = sr.Value &^ flag
+	}
+}
+
+// setSignedFlags updates the zero, negative and signed overflow flags for the result of a signed operation
+func (c *CPU) setSignedFlags(result uint32, overflow bool) {
+	c.setFlag(STATUS_ZERO, result == 0)
+	c.setFlag(STATUS_NEGATIVE, result&0x80000000 > 0)
+	c.setFlag(STATUS_SIGNED_OVERFLOW, overflow)
+}
+
+// load reads the word at the address in I1 plus offset into I2
+func (c *CPU) load(i1, i2 *Register, offset uint32) {
+	val, err := c.bus.Read(i1.Value + offset)
+	if err != nil {
+		c.fault(FAULT_MEMORY)
+		return
+	}
+	i2.Value = val
+}
+
+// store writes I1 to the address in I2 plus offset
+func (c *CPU) store(i1, i2 *Register, offset uint32) {
+	err := c.bus.Write(i2.Value+offset, i1.Value)
+	if err != nil {
+		c.fault(FAULT_MEMORY)
+	}
+}
+
+func (c *CPU) loadinc(i1, i2 *Register, offset uint32) {
+	c.load(i1, i2, offset)
+	i1.Value++
+}
+
+func (c *CPU) storeinc(i1, i2 *Register, offset uint32) {
+	c.store(i1, i2, offset)
+	i2.Value++
+}
+
+// enter saves FP, points it at the top of the stack and then reserves I1 words below it for locals
+func (c *CPU) enter(i1, _ *Register) {
+	fp, err := c.registers.GetRegister(FP)
+	if err != nil {
+		panic(err)
+	}
+	sp, err := c.registers.GetRegister(SP)
+	if err != nil {
+		panic(err)
+	}
+
+	// The locals mustn't take SP past the stack limit either, so check the whole frame before pushing anything
+	if c.interrupts != nil && uint64(sp.Value) < uint64(c.interrupts.stackLimit())+uint64(i1.Value)+1 {
+		c.stackFault(errStackOverflow)
+		return
+	}
+	err = c.stackPush(fp.Value)
+	if err != nil {
+		c.stackFault(err)
+		return
+	}
+	fp.Value = sp.Value
+	sp.Value = sp.Value - i1.Value
+}
+
+// leave drops the current frame's locals and restores the caller's FP
+func (c *CPU) leave(_, _ *Register) {
+	fp, err := c.registers.GetRegister(FP)
+	if err != nil {
+		panic(err)
+	}
+	sp, err := c.registers.GetRegister(SP)
+	if err != nil {
+		panic(err)
+	}
+
+	sp.Value = fp.Value
+	val, err := c.stackPop()
+	if err != nil {
+		c.fault(FAULT_MEMORY)
+		return
+	}
+	fp.Value = val
+}
+
+// syscall runs the host handler registered for the number in I1
+func (c *CPU) syscall(i1, _ *Register) error {
+	handler, ok := c.syscalls[i1.Value]
+	if !ok {
+		c.fault(FAULT_SYSCALL)
+		return fmt.Errorf("no handler for syscall %d", i1.Value)
+	}
+	err := handler(c.registers, c.bus)
+	if err != nil {
+		c.fault(FAULT_SYSCALL)
+		return fmt.Errorf("syscall %d failed: %v", i1.Value, err)
+	}
+	return nil
+}
+
+// stackPush decrements SP and writes value to the new top of the stack. Pushing past the stack limit set on the
+// interrupt controller gives errStackOverflow and leaves SP alone
+func (c *CPU) stackPush(value uint32) error {
+	sp, err := c.registers.GetRegister(SP)
+	if err != nil {
+		panic(err)
+	}
+	if c.interrupts != nil && sp.Value <= c.interrupts.stackLimit() {
+		return errStackOverflow
+	}
+	return c.stackPushUnchecked(value)
+}
+
+// stackPushUnchecked pushes value without checking the stack limit. Entering a handler uses it, so the handler for
+// a stack overflow can still be given somewhere to return to
+func (c *CPU) stackPushUnchecked(value uint32) error {
 	sp, err := c.registers.GetRegister(SP)
 	if err != nil {
 		panic(err)
 	}
+	sp.Value--
+	return c.bus.Write(sp.Value, value)
+}
 
+// stackPop reads the value at the top of the stack and increments SP
+func (c *CPU) stackPop() (uint32, error) {
+	sp, err := c.registers.GetRegister(SP)
+	if err != nil {
+		panic(err)
+	}
 	val, err := c.bus.Read(sp.Value)
+	if err != nil {
+		return 0, err
+	}
 	sp.Value++
+	return val, nil
+}
+
+// serviceInterrupts jumps to the handler of the next pending interrupt, if interrupts are
+// enabled. PC and SR are pushed so IRET can resume the interrupted program
+func (c *CPU) serviceInterrupts(sr *Register) error {
+	if c.interrupts == nil || sr.Value&STATUS_INTERRUPT_ENABLE == 0 {
+		return nil
+	}
+	vector, ok := c.interrupts.next()
+	if !ok {
+		return nil
+	}
+	handler, err := c.bus.Read(vector)
 	if err != nil {
-		sr, err := c.registers.GetRegister(SR)
-		if err != nil {
-			panic(err)
-		}
-		sr.Value = sr.Value | STATUS_MEMORY_ERROR
-		return
+		return fmt.Errorf("could not read interrupt vector %x: %v", vector, err)
 	}
+	if handler == 0 {
+		// No handler installed, drop the interrupt
+		return nil
+	}
+	return c.enterHandler(handler)
+}
 
+// enterHandler pushes PC and SR, disables interrupts and jumps to handler. The pushes go past the stack limit if
+// they have to, since a stack overflow is one of the faults with a handler
+func (c *CPU) enterHandler(handler uint32) error {
+	sr, err := c.registers.GetRegister(SR)
+	if err != nil {
+		return err
+	}
 	pc, err := c.registers.GetRegister(PC)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	err = c.stackPushUnchecked(pc.Value)
+	if err != nil {
+		return fmt.Errorf("could not save PC for handler: %v", err)
+	}
+	err = c.stackPushUnchecked(sr.Value)
+	if err != nil {
+		return fmt.Errorf("could not save SR for handler: %v", err)
+	}
+	sr.Value = sr.Value &^ STATUS_INTERRUPT_ENABLE
+	pc.Value = handler
+	return nil
+}
+
+// fault records that the current instruction failed with cause, setting the matching status flag. Only the first
+// fault of an instruction is kept
+func (c *CPU) fault(cause uint32) {
+	switch cause {
+	case FAULT_MEMORY, FAULT_STACK_OVERFLOW:
+		c.setFlag(STATUS_MEMORY_ERROR, true)
+	case FAULT_DIVIDE_BY_ZERO:
+		c.setFlag(STATUS_DIVIDE_BY_ZERO, true)
+	}
+	if c.pendingFault == FAULT_NONE {
+		c.pendingFault = cause
 	}
-	pc.Value = val
 }
 
-func (c *CPU) executeInstruction(instruction uint32) error {
-	opcode := uint8(instruction >> 24)
+// stackFault records the fault for an error from stackPush
+func (c *CPU) stackFault(err error) {
+	if err == errStackOverflow {
+		c.fault(FAULT_STACK_OVERFLOW)
+	} else {
+		c.fault(FAULT_MEMORY)
+	}
+}
+
+// trap jumps to the exception handler for the pending fault. It reports false when there is no handler installed,
+// in which case the machine carries on as it would without exception handling
+func (c *CPU) trap() (bool, error) {
+	cause := c.pendingFault
+	c.pendingFault = FAULT_NONE
+	if c.interrupts == nil {
+		return false, nil
+	}
+	vector := c.interrupts.exceptionVector(cause)
+	handler, err := c.bus.Read(vector)
+	if err != nil || handler == 0 {
+		return false, nil
+	}
+	c.interrupts.recordFault(cause, c.faultPC)
+	err = c.enterHandler(handler)
+	if err != nil {
+		return false, fmt.Errorf("could not enter handler for fault %d at %x: %v", cause, c.faultPC, err)
+	}
+	return true, nil
+}
+
+func (c *CPU) executeInstruction(instruction, imm uint32) error {
+	opcode := uint8(instruction>>24) &^ EXTENDED
 	regIndex1 := uint8((instruction & 0x00F00000) >> 20)
 	regIndex2 := uint8((instruction & 0x000F0000) >> 16)
-	imm := instruction & 0x0000FFFF
 
 	var i1, i2 *Register
 	var err error
@@ -364,15 +760,76 @@ func (c *CPU) executeInstruction(instruction uint32) error {
 		c.call(i1, i2)
 	case RETURN:
 		c.ret(i1, i2)
+	case IRET:
+		c.iret(i1, i2)
+	case AND:
+		c.and(i1, i2)
+	case OR:
+		c.or(i1, i2)
+	case XOR:
+		c.xor(i1, i2)
+	case NOT:
+		c.not(i1, i2)
+	case SHL:
+		c.shl(i1, i2)
+	case SHR:
+		c.shr(i1, i2)
+	case SAR:
+		c.sar(i1, i2)
+	case ROL:
+		c.rol(i1, i2)
+	case ROR:
+		c.ror(i1, i2)
+	case ADDS:
+		c.adds(i1, i2)
+	case SUBS:
+		c.subs(i1, i2)
+	case MULS:
+		c.muls(i1, i2)
+	case DIVS:
+		c.divs(i1, i2)
+	case MOD:
+		c.mod(i1, i2)
+	case LESSS:
+		c.lesss(i1, i2)
+	case LTES:
+		c.ltes(i1, i2)
+	case GTS:
+		c.gts(i1, i2)
+	case GTES:
+		c.gtes(i1, i2)
+	case LOAD:
+		c.load(i1, i2, displacement(instruction, imm))
+	case STORE:
+		c.store(i1, i2, displacement(instruction, imm))
+	case LOADINC:
+		c.loadinc(i1, i2, displacement(instruction, imm))
+	case STOREINC:
+		c.storeinc(i1, i2, displacement(instruction, imm))
+	case ENTER:
+		c.enter(i1, i2)
+	case LEAVE:
+		c.leave(i1, i2)
+	case SYSCALL:
+		return c.syscall(i1, i2)
 	default:
-		// Halt the machine if we can't figure out the instruction
-		c.set(&Register{1}, &Register{1})
+		// Tick will halt the machine if there is no handler for this
+		c.fault(FAULT_ILLEGAL_INSTRUCTION)
 		return fmt.Errorf("unrecognised opcode '%x'", opcode)
 	}
 
 	return nil
 }
 
+// displacement gives the signed offset carried in an instruction's immediate data. The 16-bit field is sign
+// extended, while the word following an extended instruction is used as it is
+func displacement(instruction, imm uint32) uint32 {
+	if (instruction>>24)&EXTENDED > 0 {
+		return imm
+	}
+	return uint32(int32(int16(imm)))
+}
+
 func (c *CPU) Tick() error {
 	sr, err := c.registers.GetRegister(SR)
 	if err != nil {
@@ -381,29 +838,73 @@ func (c *CPU) Tick() error {
 	if sr.Value&STATUS_HALT > 0 {
 		return fmt.Errorf("cannot tick on a Halted machine")
 	}
-	ir, err := c.registers.GetRegister(IR)
+	defer c.bus.Tick()
+	err = c.serviceInterrupts(sr)
 	if err != nil {
+		sr.Value = sr.Value | STATUS_HALT
 		return err
 	}
-	pc, err := c.registers.GetRegister(PC)
+	ir, err := c.registers.GetRegister(IR)
 	if err != nil {
 		return err
 	}
-	ir.Value, err = c.bus.Read(pc.Value)
+	pc, err := c.registers.GetRegister(PC)
 	if err != nil {
 		return err
 	}
-	pc.Value++
-	err = c.executeInstruction(ir.Value)
+	c.faultPC = pc.Value
+	err = c.fetchAndExecute(ir, pc)
+	if c.pendingFault != FAULT_NONE {
+		handled, trapErr := c.trap()
+		if trapErr != nil {
+			sr.Value = sr.Value | STATUS_HALT
+			return trapErr
+		}
+		if handled {
+			return nil
+		}
+	}
 	if err != nil {
 		sr.Value = sr.Value | STATUS_HALT
 	}
 	return err
 }
 
+// fetchAndExecute reads the instruction at PC, along with its immediate word if it is extended, and runs it
+func (c *CPU) fetchAndExecute(ir, pc *Register) error {
+	var err error
+	ir.Value, err = c.bus.Fetch(pc.Value)
+	if err != nil {
+		c.fault(FAULT_MEMORY)
+		return err
+	}
+	pc.Value++
+	imm := ir.Value & 0x0000FFFF
+	if (ir.Value>>24)&EXTENDED > 0 {
+		imm, err = c.bus.Fetch(pc.Value)
+		if err != nil {
+			c.fault(FAULT_MEMORY)
+			return err
+		}
+		pc.Value++
+	}
+	return c.executeInstruction(ir.Value, imm)
+}
+
+// AttachInterruptController lets the CPU receive interrupts raised on ic
+func (c *CPU) AttachInterruptController(ic *InterruptController) {
+	c.interrupts = ic
+}
+
+// RegisterSyscall makes handler available to guest programs as SYSCALL n, replacing any handler already there
+func (c *CPU) RegisterSyscall(n uint32, handler SyscallHandler) {
+	c.syscalls[n] = handler
+}
+
 func NewCPU(registers *RegisterBank, bus *Bus) *CPU {
 	return &CPU{
 		registers: registers,
 		bus:       bus,
+		syscalls:  map[uint32]SyscallHandler{},
 	}
 }
@@ -1,6 +1,7 @@
 package machine
 
 import (
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -8,7 +9,7 @@ import (
 func TestCPU(t *testing.T) {
 	t.Run("test halt", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		bus.Write(0x100, 0x00)
@@ -23,7 +24,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test read", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[R0].Value = 0x1000
@@ -36,7 +37,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test write", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[R0].Value = 0xFFFF
@@ -51,7 +52,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test copy", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[R0].Value = 0xFFFF
@@ -63,7 +64,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test add", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[R1].Value = 0x10
@@ -76,7 +77,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test add with overflow", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[R1].Value = 0xFFFFFFFE
@@ -89,7 +90,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test sub", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[R1].Value = 0x02
@@ -102,7 +103,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test sub with underflow check", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[R1].Value = 0x05
@@ -110,12 +111,12 @@ func TestCPU(t *testing.T) {
 
 		err := cpu.Tick()
 		assert.NoError(t, err)
-		assert.Equal(t, uint32(0xFFFFFFFD), registers.registerMap[R1].Value)
+		assert.Equal(t, uint32(0xFFFFFFFE), registers.registerMap[R1].Value)
 		assert.Equal(t, STATUS_UNDERFLOW, registers.registerMap[SR].Value)
 	})
 	t.Run("test mul", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[R1].Value = 0x10
@@ -128,7 +129,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test mul with overflow", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[R1].Value = 0xFFFFFFFE
@@ -141,7 +142,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test div", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[R1].Value = 0x03
@@ -154,7 +155,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test div with divide by zero", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[R1].Value = 0x00
@@ -167,7 +168,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test stat flag unset", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[SR].Value = 0x00000000
@@ -180,7 +181,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test stat flag set", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[SR].Value = 0x0000000A
@@ -193,7 +194,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test set flag", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[SR].Value = 0x00000000
@@ -206,7 +207,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test reset flag", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[SR].Value = 0x0000000A
@@ -217,9 +218,22 @@ func TestCPU(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, uint32(0x08), registers.registerMap[SR].Value)
 	})
+	t.Run("test reset flag already unset", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[SR].Value = 0x00000008
+		registers.registerMap[R1].Value = 0x00
+		bus.Write(0x100, 0x09F10002)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x08), registers.registerMap[SR].Value)
+	})
 	t.Run("run invalid instruction", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		bus.Write(0x100, 0xFFF10002)
@@ -230,7 +244,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test push", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[SR].Value = 0x00000000
@@ -246,7 +260,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test pop", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[SR].Value = 0x00000000
@@ -263,7 +277,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test jmp", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[SR].Value = 0x00000000
@@ -277,7 +291,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test less", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[SR].Value = 0x00000000
@@ -296,7 +310,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test lte", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[SR].Value = 0x00000000
@@ -320,7 +334,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test gt", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[SR].Value = 0x00000000
@@ -339,7 +353,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test gte", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[SR].Value = 0x00000000
@@ -363,7 +377,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test eq", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		registers.registerMap[SR].Value = 0x00000000
@@ -382,7 +396,7 @@ func TestCPU(t *testing.T) {
 	})
 	t.Run("test call/return", func(t *testing.T) {
 		registers := NewRegisterBank()
-		bus := NewBus(NewMemory())
+		bus := newBus(t, NewMemory())
 		cpu := NewCPU(registers, bus)
 
 		bus.Write(0x100, 0x12F10200)
@@ -396,4 +410,858 @@ func TestCPU(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, uint32(0x101), registers.registerMap[PC].Value)
 	})
+	t.Run("test interrupt dispatch", func(t *testing.T) {
+		registers := NewRegisterBank()
+		ic := NewInterruptController()
+		bus := newBus(t, NewMemory(), ic)
+		cpu := NewCPU(registers, bus)
+		cpu.AttachInterruptController(ic)
+
+		registers.registerMap[SR].Value = STATUS_INTERRUPT_ENABLE
+		bus.Write(0x02, 0x200)
+		bus.Write(0x100, 0x03F00001)
+		bus.Write(0x200, 0x03F10002)
+		ic.Raise(2)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x201), registers.registerMap[PC].Value)
+		assert.Equal(t, uint32(0x02), registers.registerMap[R1].Value)
+		assert.Equal(t, uint32(0x00), registers.registerMap[R0].Value)
+		assert.Equal(t, uint32(0x00), registers.registerMap[SR].Value)
+		assert.Equal(t, uint32(0xFFDE), registers.registerMap[SP].Value)
+		savedPC, _ := bus.Read(0xFFDF)
+		assert.Equal(t, uint32(0x100), savedPC)
+		savedSR, _ := bus.Read(0xFFDE)
+		assert.Equal(t, STATUS_INTERRUPT_ENABLE, savedSR)
+	})
+	t.Run("test interrupts disabled", func(t *testing.T) {
+		registers := NewRegisterBank()
+		ic := NewInterruptController()
+		bus := newBus(t, NewMemory(), ic)
+		cpu := NewCPU(registers, bus)
+		cpu.AttachInterruptController(ic)
+
+		bus.Write(0x02, 0x200)
+		bus.Write(0x100, 0x03F00001)
+		ic.Raise(2)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x101), registers.registerMap[PC].Value)
+		assert.Equal(t, uint32(0x01), registers.registerMap[R0].Value)
+		assert.Equal(t, uint32(0x04), ic.pending)
+	})
+	t.Run("test interrupt without handler", func(t *testing.T) {
+		registers := NewRegisterBank()
+		ic := NewInterruptController()
+		bus := newBus(t, NewMemory(), ic)
+		cpu := NewCPU(registers, bus)
+		cpu.AttachInterruptController(ic)
+
+		registers.registerMap[SR].Value = STATUS_INTERRUPT_ENABLE
+		bus.Write(0x100, 0x03F00001)
+		ic.Raise(2)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x101), registers.registerMap[PC].Value)
+		assert.Equal(t, uint32(0xFFE0), registers.registerMap[SP].Value)
+	})
+	t.Run("test iret", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[PC].Value = 0x200
+		registers.registerMap[SP].Value = 0xFFDE
+		bus.Write(0x200, 0x14000000)
+		bus.Write(0xFFDF, 0x100)
+		bus.Write(0xFFDE, STATUS_INTERRUPT_ENABLE)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x100), registers.registerMap[PC].Value)
+		assert.Equal(t, STATUS_INTERRUPT_ENABLE, registers.registerMap[SR].Value)
+		assert.Equal(t, uint32(0xFFE0), registers.registerMap[SP].Value)
+	})
+	t.Run("test and", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0x1234
+		bus.Write(0x100, 0x15F100F0)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x30), registers.registerMap[R1].Value)
+	})
+	t.Run("test or", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0x1200
+		bus.Write(0x100, 0x16F10034)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x1234), registers.registerMap[R1].Value)
+	})
+	t.Run("test xor", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0xFF00
+		bus.Write(0x100, 0x17F10FF0)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xF0F0), registers.registerMap[R1].Value)
+	})
+	t.Run("test not", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R0].Value = 0x0F0F0F0F
+		bus.Write(0x100, 0x18010000)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xF0F0F0F0), registers.registerMap[R1].Value)
+	})
+	t.Run("test shl", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0x40000001
+		bus.Write(0x100, 0x19F10001)
+		bus.Write(0x101, 0x19F10001)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x80000002), registers.registerMap[R1].Value)
+		assert.Equal(t, uint32(0x0), registers.registerMap[SR].Value)
+
+		err = cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x04), registers.registerMap[R1].Value)
+		assert.Equal(t, STATUS_CARRY, registers.registerMap[SR].Value)
+	})
+	t.Run("test shl past word size", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0xFFFFFFFF
+		bus.Write(0x100, 0x19F10021)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x0), registers.registerMap[R1].Value)
+		assert.Equal(t, uint32(0x0), registers.registerMap[SR].Value)
+	})
+	t.Run("test shr", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0x80000006
+		bus.Write(0x100, 0x1AF10002)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x20000001), registers.registerMap[R1].Value)
+		assert.Equal(t, STATUS_CARRY, registers.registerMap[SR].Value)
+	})
+	t.Run("test shr clears carry", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[SR].Value = STATUS_CARRY
+		registers.registerMap[R1].Value = 0x04
+		bus.Write(0x100, 0x1AF10002)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x01), registers.registerMap[R1].Value)
+		assert.Equal(t, uint32(0x0), registers.registerMap[SR].Value)
+	})
+	t.Run("test sar", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0x80000018
+		bus.Write(0x100, 0x1BF10004)
+		bus.Write(0x101, 0x1BF10028)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xF8000001), registers.registerMap[R1].Value)
+		assert.Equal(t, STATUS_CARRY, registers.registerMap[SR].Value)
+
+		err = cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xFFFFFFFF), registers.registerMap[R1].Value)
+		assert.Equal(t, STATUS_CARRY, registers.registerMap[SR].Value)
+	})
+	t.Run("test rol", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0x80000001
+		bus.Write(0x100, 0x1CF10004)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x00000018), registers.registerMap[R1].Value)
+		assert.Equal(t, uint32(0x0), registers.registerMap[SR].Value)
+	})
+	t.Run("test ror", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0x00000011
+		bus.Write(0x100, 0x1DF10001)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x80000008), registers.registerMap[R1].Value)
+		assert.Equal(t, STATUS_CARRY, registers.registerMap[SR].Value)
+	})
+	t.Run("test adds", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R0].Value = 0xFFFFFFFB // -5
+		registers.registerMap[R1].Value = 0x03
+		bus.Write(0x100, 0x1E010000)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xFFFFFFFE), registers.registerMap[R1].Value)
+		assert.Equal(t, STATUS_NEGATIVE, registers.registerMap[SR].Value)
+	})
+	t.Run("test adds to zero", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R0].Value = 0xFFFFFFFD // -3
+		registers.registerMap[R1].Value = 0x03
+		bus.Write(0x100, 0x1E010000)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x0), registers.registerMap[R1].Value)
+		assert.Equal(t, STATUS_ZERO|STATUS_CARRY, registers.registerMap[SR].Value)
+	})
+	t.Run("test adds with signed overflow", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0x7FFFFFFF
+		bus.Write(0x100, 0x1EF10001)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x80000000), registers.registerMap[R1].Value)
+		assert.Equal(t, STATUS_NEGATIVE|STATUS_SIGNED_OVERFLOW, registers.registerMap[SR].Value)
+	})
+	t.Run("test subs", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0x05
+		bus.Write(0x100, 0x1FF10003)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xFFFFFFFE), registers.registerMap[R1].Value)
+		assert.Equal(t, STATUS_NEGATIVE|STATUS_CARRY, registers.registerMap[SR].Value)
+	})
+	t.Run("test subs with signed overflow", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R0].Value = 0x80000000
+		registers.registerMap[R1].Value = 0x01
+		bus.Write(0x100, 0x1F010000)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x7FFFFFFF), registers.registerMap[R1].Value)
+		assert.Equal(t, STATUS_SIGNED_OVERFLOW, registers.registerMap[SR].Value)
+	})
+	t.Run("test muls", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0xFFFFFFFD // -3
+		bus.Write(0x100, 0x20F10004)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xFFFFFFF4), registers.registerMap[R1].Value)
+		assert.Equal(t, STATUS_NEGATIVE, registers.registerMap[SR].Value)
+	})
+	t.Run("test muls with signed overflow", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0x40000000
+		bus.Write(0x100, 0x20F10002)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x80000000), registers.registerMap[R1].Value)
+		assert.Equal(t, STATUS_NEGATIVE|STATUS_SIGNED_OVERFLOW, registers.registerMap[SR].Value)
+	})
+	t.Run("test divs", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R0].Value = 0xFFFFFFF9 // -7
+		registers.registerMap[R1].Value = 0x02
+		bus.Write(0x100, 0x21010000)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xFFFFFFFD), registers.registerMap[R1].Value)
+		assert.Equal(t, STATUS_NEGATIVE, registers.registerMap[SR].Value)
+	})
+	t.Run("test divs with divide by zero", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R0].Value = 0xFFFFFFF9
+		registers.registerMap[R1].Value = 0x00
+		bus.Write(0x100, 0x21010000)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x00), registers.registerMap[R1].Value)
+		assert.Equal(t, STATUS_DIVIDE_BY_ZERO, registers.registerMap[SR].Value)
+	})
+	t.Run("test divs with signed overflow", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R0].Value = 0x80000000
+		registers.registerMap[R1].Value = 0xFFFFFFFF
+		bus.Write(0x100, 0x21010000)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x80000000), registers.registerMap[R1].Value)
+		assert.Equal(t, STATUS_NEGATIVE|STATUS_SIGNED_OVERFLOW, registers.registerMap[SR].Value)
+	})
+	t.Run("test mod", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R0].Value = 0xFFFFFFF9 // -7
+		registers.registerMap[R1].Value = 0x03
+		bus.Write(0x100, 0x22010000)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xFFFFFFFF), registers.registerMap[R1].Value)
+		assert.Equal(t, STATUS_NEGATIVE, registers.registerMap[SR].Value)
+	})
+	t.Run("test mod with divide by zero", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0x00
+		bus.Write(0x100, 0x22F10009)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x00), registers.registerMap[R1].Value)
+		assert.Equal(t, STATUS_DIVIDE_BY_ZERO, registers.registerMap[SR].Value)
+	})
+	t.Run("test lesss", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R0].Value = 0xFFFFFFFF // -1
+		registers.registerMap[R1].Value = 0x01
+		bus.Write(0x100, 0x23010000)
+		bus.Write(0x101, 0x23100000)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x101), registers.registerMap[PC].Value)
+
+		err = cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x103), registers.registerMap[PC].Value)
+	})
+	t.Run("test ltes", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R0].Value = 0xFFFFFFFF // -1
+		registers.registerMap[R1].Value = 0xFFFFFFFF
+		bus.Write(0x100, 0x24010000)
+		bus.Write(0x101, 0x24F00000)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x101), registers.registerMap[PC].Value)
+
+		err = cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x103), registers.registerMap[PC].Value)
+	})
+	t.Run("test gts", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R0].Value = 0xFFFFFFFF // -1
+		bus.Write(0x100, 0x25F00000)
+		bus.Write(0x101, 0x250F0000)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x101), registers.registerMap[PC].Value)
+
+		err = cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x103), registers.registerMap[PC].Value)
+	})
+	t.Run("test gtes", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R0].Value = 0xFFFFFFFE // -2
+		registers.registerMap[R1].Value = 0xFFFFFFFF // -1
+		bus.Write(0x100, 0x26100000)
+		bus.Write(0x101, 0x26010000)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x101), registers.registerMap[PC].Value)
+
+		err = cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x103), registers.registerMap[PC].Value)
+	})
+	t.Run("test extended immediate", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		bus.Write(0x100, 0x83F00000)
+		bus.Write(0x101, 0x12345678)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x12345678), registers.registerMap[R0].Value)
+		assert.Equal(t, uint32(0x102), registers.registerMap[PC].Value)
+	})
+	t.Run("test skip extended instruction", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0x02
+		bus.Write(0x100, 0x11F10001)
+		bus.Write(0x101, 0x83F00000)
+		bus.Write(0x102, 0x12345678)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x103), registers.registerMap[PC].Value)
+		assert.Equal(t, uint32(0x00), registers.registerMap[R0].Value)
+	})
+	t.Run("test load", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0x1000
+		bus.Write(0x100, 0x27100004)
+		bus.Write(0x101, 0x2712FFFF)
+		bus.Write(0x1004, 0xAAAA)
+		bus.Write(0x0FFF, 0xBBBB)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xAAAA), registers.registerMap[R0].Value)
+
+		err = cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xBBBB), registers.registerMap[R2].Value)
+		assert.Equal(t, uint32(0x1000), registers.registerMap[R1].Value)
+	})
+	t.Run("test load with extended offset", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0x00010000
+		bus.Write(0x100, 0xA7100000)
+		bus.Write(0x101, 0xFFFF1000)
+		bus.Write(0x1000, 0xAAAA)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xAAAA), registers.registerMap[R0].Value)
+		assert.Equal(t, uint32(0x102), registers.registerMap[PC].Value)
+	})
+	t.Run("test load from unmapped address", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R1].Value = 0xFFFF0000
+		bus.Write(0x100, 0x27100000)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, STATUS_MEMORY_ERROR, registers.registerMap[SR].Value)
+	})
+	t.Run("test store", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R0].Value = 0xCCCC
+		registers.registerMap[R1].Value = 0x1000
+		bus.Write(0x100, 0x28010010)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		v, err := bus.Read(0x1010)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xCCCC), v)
+	})
+	t.Run("test loadinc", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R0].Value = 0x1000
+		bus.Write(0x100, 0x29010000)
+		bus.Write(0x101, 0x29020000)
+		bus.Write(0x1000, 0x68)
+		bus.Write(0x1001, 0x69)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		err = cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x68), registers.registerMap[R1].Value)
+		assert.Equal(t, uint32(0x69), registers.registerMap[R2].Value)
+		assert.Equal(t, uint32(0x1002), registers.registerMap[R0].Value)
+	})
+	t.Run("test storeinc", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[R0].Value = 0x68
+		registers.registerMap[R1].Value = 0x1000
+		bus.Write(0x100, 0x2A010001)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		v, err := bus.Read(0x1001)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x68), v)
+		assert.Equal(t, uint32(0x1001), registers.registerMap[R1].Value)
+	})
+	t.Run("test enter/leave", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		registers.registerMap[FP].Value = 0xABCD
+		bus.Write(0x100, 0x2BF00003)
+		bus.Write(0x101, 0x2C000000)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xFFDF), registers.registerMap[FP].Value)
+		assert.Equal(t, uint32(0xFFDC), registers.registerMap[SP].Value)
+		saved, err := bus.Read(0xFFDF)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xABCD), saved)
+
+		err = cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xABCD), registers.registerMap[FP].Value)
+		assert.Equal(t, uint32(0xFFE0), registers.registerMap[SP].Value)
+	})
+	t.Run("test divide by zero trap", func(t *testing.T) {
+		registers := NewRegisterBank()
+		ic := NewInterruptController()
+		bus := newBus(t, NewMemory(), ic)
+		cpu := NewCPU(registers, bus)
+		cpu.AttachInterruptController(ic)
+
+		bus.Write(EXCEPTION_VECTORS+FAULT_DIVIDE_BY_ZERO, 0x200)
+		bus.Write(0x100, 0x07F10003)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x200), registers.registerMap[PC].Value)
+		assert.Equal(t, uint32(0xFFDE), registers.registerMap[SP].Value)
+		returnPC, _ := bus.Read(0xFFDF)
+		assert.Equal(t, uint32(0x101), returnPC)
+		savedSR, _ := bus.Read(0xFFDE)
+		assert.Equal(t, STATUS_DIVIDE_BY_ZERO, savedSR)
+		cause, _ := bus.Read(INTERRUPT_FAULT_CAUSE)
+		assert.Equal(t, FAULT_DIVIDE_BY_ZERO, cause)
+		faultPC, _ := bus.Read(INTERRUPT_FAULT_PC)
+		assert.Equal(t, uint32(0x100), faultPC)
+	})
+	t.Run("test memory fault trap", func(t *testing.T) {
+		registers := NewRegisterBank()
+		ic := NewInterruptController()
+		bus := newBus(t, NewMemory(), ic)
+		cpu := NewCPU(registers, bus)
+		cpu.AttachInterruptController(ic)
+
+		registers.registerMap[R0].Value = 0x12345
+		bus.Write(EXCEPTION_VECTORS+FAULT_MEMORY, 0x200)
+		bus.Write(0x100, 0x01010000)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x200), registers.registerMap[PC].Value)
+		cause, _ := bus.Read(INTERRUPT_FAULT_CAUSE)
+		assert.Equal(t, FAULT_MEMORY, cause)
+	})
+	t.Run("test illegal instruction trap", func(t *testing.T) {
+		registers := NewRegisterBank()
+		ic := NewInterruptController()
+		bus := newBus(t, NewMemory(), ic)
+		cpu := NewCPU(registers, bus)
+		cpu.AttachInterruptController(ic)
+
+		bus.Write(EXCEPTION_VECTORS+FAULT_ILLEGAL_INSTRUCTION, 0x200)
+		bus.Write(0x100, 0x7F000000)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x200), registers.registerMap[PC].Value)
+		assert.Equal(t, uint32(0x0), registers.registerMap[SR].Value&STATUS_HALT)
+		cause, _ := bus.Read(INTERRUPT_FAULT_CAUSE)
+		assert.Equal(t, FAULT_ILLEGAL_INSTRUCTION, cause)
+	})
+	t.Run("test illegal instruction without handler", func(t *testing.T) {
+		registers := NewRegisterBank()
+		ic := NewInterruptController()
+		bus := newBus(t, NewMemory(), ic)
+		cpu := NewCPU(registers, bus)
+		cpu.AttachInterruptController(ic)
+
+		bus.Write(0x100, 0x7F000000)
+
+		err := cpu.Tick()
+		assert.Error(t, err)
+		assert.Equal(t, STATUS_HALT, registers.registerMap[SR].Value)
+	})
+	t.Run("test stack overflow", func(t *testing.T) {
+		registers := NewRegisterBank()
+		ic := NewInterruptController()
+		bus := newBus(t, NewMemory(), ic)
+		cpu := NewCPU(registers, bus)
+		cpu.AttachInterruptController(ic)
+
+		bus.Write(INTERRUPT_STACK_LIMIT, 0xFFDF)
+		bus.Write(0x100, 0x0AF00001)
+		bus.Write(0x101, 0x0AF00002)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xFFDF), registers.registerMap[SP].Value)
+		assert.Equal(t, uint32(0x0), registers.registerMap[SR].Value)
+
+		err = cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xFFDF), registers.registerMap[SP].Value)
+		assert.Equal(t, STATUS_MEMORY_ERROR, registers.registerMap[SR].Value)
+	})
+	t.Run("test enter stack overflow", func(t *testing.T) {
+		registers := NewRegisterBank()
+		ic := NewInterruptController()
+		bus := newBus(t, NewMemory(), ic)
+		cpu := NewCPU(registers, bus)
+		cpu.AttachInterruptController(ic)
+
+		// The saved FP and two locals reach the limit exactly, but three locals would go past it
+		bus.Write(INTERRUPT_STACK_LIMIT, 0xFFDD)
+		bus.Write(0x100, 0x2BF00003)
+		bus.Write(0x101, 0x2BF00002)
+		registers.registerMap[FP].Value = 0xABCD
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xFFE0), registers.registerMap[SP].Value)
+		assert.Equal(t, uint32(0xABCD), registers.registerMap[FP].Value)
+		assert.Equal(t, STATUS_MEMORY_ERROR, registers.registerMap[SR].Value)
+
+		err = cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0xFFDD), registers.registerMap[SP].Value)
+		assert.Equal(t, uint32(0xFFDF), registers.registerMap[FP].Value)
+	})
+	t.Run("test stack overflow trap", func(t *testing.T) {
+		registers := NewRegisterBank()
+		ic := NewInterruptController()
+		bus := newBus(t, NewMemory(), ic)
+		cpu := NewCPU(registers, bus)
+		cpu.AttachInterruptController(ic)
+
+		bus.Write(INTERRUPT_STACK_LIMIT, 0xFF00)
+		bus.Write(EXCEPTION_VECTORS+FAULT_STACK_OVERFLOW, 0x200)
+		registers.registerMap[SP].Value = 0xFF00
+		// CALL 0x300 overflows the stack, and the handler returns straight away
+		bus.Write(0x100, 0x12F00300)
+		bus.Write(0x200, 0x14000000)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x200), registers.registerMap[PC].Value)
+		assert.Equal(t, uint32(0x0), registers.registerMap[SR].Value&STATUS_HALT)
+		assert.Equal(t, uint32(0xFEFE), registers.registerMap[SP].Value)
+		cause, _ := bus.Read(INTERRUPT_FAULT_CAUSE)
+		assert.Equal(t, FAULT_STACK_OVERFLOW, cause)
+		faultPC, _ := bus.Read(INTERRUPT_FAULT_PC)
+		assert.Equal(t, uint32(0x100), faultPC)
+
+		err = cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x101), registers.registerMap[PC].Value)
+		assert.Equal(t, uint32(0xFF00), registers.registerMap[SP].Value)
+		assert.Equal(t, uint32(0x0), registers.registerMap[SR].Value&STATUS_HALT)
+	})
+	t.Run("test syscall", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		cpu.RegisterSyscall(0x02, func(rb *RegisterBank, b *Bus) error {
+			r0, err := rb.GetRegister(R0)
+			if err != nil {
+				return err
+			}
+			v, err := b.Read(r0.Value)
+			if err != nil {
+				return err
+			}
+			r0.Value = v * 2
+			return nil
+		})
+		registers.registerMap[R0].Value = 0x1000
+		bus.Write(0x1000, 0x15)
+		bus.Write(0x100, 0x2DF00002)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x2A), registers.registerMap[R0].Value)
+	})
+	t.Run("test unregistered syscall", func(t *testing.T) {
+		registers := NewRegisterBank()
+		bus := newBus(t, NewMemory())
+		cpu := NewCPU(registers, bus)
+
+		bus.Write(0x100, 0x2DF00002)
+
+		err := cpu.Tick()
+		assert.Error(t, err)
+		assert.Equal(t, STATUS_HALT, registers.registerMap[SR].Value)
+	})
+	t.Run("test failed syscall trap", func(t *testing.T) {
+		registers := NewRegisterBank()
+		ic := NewInterruptController()
+		bus := newBus(t, NewMemory(), ic)
+		cpu := NewCPU(registers, bus)
+		cpu.AttachInterruptController(ic)
+
+		cpu.RegisterSyscall(0x01, func(_ *RegisterBank, _ *Bus) error {
+			return fmt.Errorf("no such file")
+		})
+		bus.Write(EXCEPTION_VECTORS+FAULT_SYSCALL, 0x200)
+		bus.Write(0x100, 0x2DF00001)
+
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x200), registers.registerMap[PC].Value)
+		cause, _ := bus.Read(INTERRUPT_FAULT_CAUSE)
+		assert.Equal(t, FAULT_SYSCALL, cause)
+	})
+	t.Run("test write to protected memory", func(t *testing.T) {
+		registers := NewRegisterBank()
+		mem := NewMemory()
+		bus := newBus(t, mem)
+		cpu := NewCPU(registers, bus)
+		registers.registerMap[R0].Value = 0x12
+		bus.Write(0x100, 0x020F0100)
+		mem.Protect(0x100, 0x1FF, MEMORY_READ|MEMORY_EXECUTE)
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, STATUS_MEMORY_ERROR, registers.registerMap[SR].Value)
+		word, _ := bus.Read(0x100)
+		assert.Equal(t, uint32(0x020F0100), word)
+	})
+	t.Run("test protected write trap", func(t *testing.T) {
+		registers := NewRegisterBank()
+		mem := NewMemory()
+		ic := NewInterruptController()
+		bus := newBus(t, mem, ic)
+		cpu := NewCPU(registers, bus)
+		cpu.AttachInterruptController(ic)
+		bus.Write(EXCEPTION_VECTORS+FAULT_MEMORY, 0x200)
+		bus.Write(0x100, 0x020F0100)
+		mem.Protect(0x100, 0x1FF, MEMORY_READ|MEMORY_EXECUTE)
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x200), registers.registerMap[PC].Value)
+		cause, _ := bus.Read(INTERRUPT_FAULT_CAUSE)
+		assert.Equal(t, FAULT_MEMORY, cause)
+	})
+	t.Run("test execute non-executable memory", func(t *testing.T) {
+		registers := NewRegisterBank()
+		mem := NewMemory()
+		ic := NewInterruptController()
+		bus := newBus(t, mem, ic)
+		cpu := NewCPU(registers, bus)
+		cpu.AttachInterruptController(ic)
+		mem.Protect(0x100, 0x1FF, MEMORY_READ|MEMORY_WRITE)
+
+		bus.Write(EXCEPTION_VECTORS+FAULT_MEMORY, 0x200)
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x200), registers.registerMap[PC].Value)
+		faultPC, _ := bus.Read(INTERRUPT_FAULT_PC)
+		assert.Equal(t, uint32(0x100), faultPC)
+	})
 }
@@ -0,0 +1,240 @@
+package machine
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// DISK_SECTOR_SIZE is the number of words in a sector
+const DISK_SECTOR_SIZE = 128
+
+// diskSectorBytes is the size of a sector in the image, each word is stored as 4 little endian bytes
+const diskSectorBytes = DISK_SECTOR_SIZE * 4
+
+const (
+	DISK_SECTOR = uint32(0xFFEB) + iota
+	DISK_BUFFER
+	DISK_COMMAND
+	DISK_STATUS
+)
+
+// Commands written to DISK_COMMAND
+const (
+	// DISK_READ copies the sector into memory at the buffer address
+	DISK_READ = uint32(iota + 1)
+	// DISK_WRITE copies the words at the buffer address into the sector
+	DISK_WRITE
+)
+
+// Bits in DISK_STATUS
+const (
+	// DISK_DONE is set when a command finishes
+	DISK_DONE uint32 = 1 << iota
+	// DISK_ERROR is set when a command fails, for example because the sector is past the end of the disk
+	DISK_ERROR
+)
+
+// DiskImage is the storage behind a disk. *os.File is a DiskImage
+type DiskImage interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// memoryImage is a DiskImage held in memory
+type memoryImage []byte
+
+func (m memoryImage) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(m)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (m memoryImage) WriteAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(m)) {
+		return 0, io.ErrShortWrite
+	}
+	n := copy(m[off:], p)
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+// DiskDevice is a bus device that copies fixed size sectors between a disk image and memory
+type DiskDevice struct {
+	lock       sync.Mutex
+	image      DiskImage
+	sectors    uint32
+	sector     uint32
+	buffer     uint32
+	status     uint32
+	bus        *Bus
+	interrupts *InterruptController
+}
+
+func (d *DiskDevice) MemoryRange() *MemoryRange {
+	// Addresses:
+	// * 0xFFEB - Sector to read or write
+	// * 0xFFEC - Address of the buffer in memory
+	// * 0xFFED - Write a command to run it
+	// * 0xFFEE - Status bits, writing a 1 to a bit clears it
+	return &MemoryRange{
+		Start: 0xFFEB,
+		End:   0xFFEE,
+	}
+}
+
+func (d *DiskDevice) Read(address uint32) (uint32, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	switch address {
+	case DISK_SECTOR:
+		return d.sector, nil
+	case DISK_BUFFER:
+		return d.buffer, nil
+	case DISK_STATUS:
+		return d.status, nil
+	}
+	return 0, nil
+}
+
+func (d *DiskDevice) Write(address, value uint32) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	switch address {
+	case DISK_SECTOR:
+		d.sector = value
+	case DISK_BUFFER:
+		d.buffer = value
+	case DISK_COMMAND:
+		return d.run(value)
+	case DISK_STATUS:
+		d.status = d.status &^ value
+	}
+	return nil
+}
+
+func (d *DiskDevice) ConnectBus(b *Bus) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.bus = b
+}
+
+// Sectors gives the number of sectors on the disk
+func (d *DiskDevice) Sectors() uint32 {
+	return d.sectors
+}
+
+// Close closes the disk image if it needs closing
+func (d *DiskDevice) Close() error {
+	if c, ok := d.image.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (d *DiskDevice) run(command uint32) error {
+	var err error
+	switch command {
+	case DISK_READ:
+		err = d.readSector()
+	case DISK_WRITE:
+		err = d.writeSector()
+	default:
+		return fmt.Errorf("unknown disk command %d", command)
+	}
+	if err != nil {
+		d.status = d.status | DISK_ERROR
+	}
+	d.status = d.status | DISK_DONE
+	if d.interrupts != nil {
+		d.interrupts.Raise(IRQ_DISK)
+	}
+	return nil
+}
+
+func (d *DiskDevice) readSector() error {
+	if err := d.checkTransfer(); err != nil {
+		return err
+	}
+	data := make([]byte, diskSectorBytes)
+	_, err := d.image.ReadAt(data, int64(d.sector)*diskSectorBytes)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	for i := uint32(0); i < DISK_SECTOR_SIZE; i++ {
+		err = d.bus.Write(d.buffer+i, binary.LittleEndian.Uint32(data[i*4:]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *DiskDevice) writeSector() error {
+	if err := d.checkTransfer(); err != nil {
+		return err
+	}
+	data := make([]byte, diskSectorBytes)
+	for i := uint32(0); i < DISK_SECTOR_SIZE; i++ {
+		word, err := d.bus.Read(d.buffer + i)
+		if err != nil {
+			return err
+		}
+		binary.LittleEndian.PutUint32(data[i*4:], word)
+	}
+	_, err := d.image.WriteAt(data, int64(d.sector)*diskSectorBytes)
+	return err
+}
+
+func (d *DiskDevice) checkTransfer() error {
+	if d.bus == nil {
+		return errors.New("disk is not connected to a bus")
+	}
+	if d.sector >= d.sectors {
+		return fmt.Errorf("sector %d is past the end of the disk", d.sector)
+	}
+	// The disk can't copy a sector over its own registers
+	if d.buffer <= DISK_STATUS && d.buffer+DISK_SECTOR_SIZE > DISK_SECTOR {
+		return fmt.Errorf("buffer %x overlaps the disk registers", d.buffer)
+	}
+	return nil
+}
+
+// NewDisk creates a disk with the given number of sectors stored in image. interrupts may be nil if the disk
+// should never raise IRQ_DISK
+func NewDisk(image DiskImage, sectors uint32, interrupts *InterruptController) *DiskDevice {
+	return &DiskDevice{
+		image:      image,
+		sectors:    sectors,
+		interrupts: interrupts,
+	}
+}
+
+// NewMemoryDisk creates a blank disk held in memory
+func NewMemoryDisk(sectors uint32, interrupts *InterruptController) *DiskDevice {
+	return NewDisk(make(memoryImage, sectors*diskSectorBytes), sectors, interrupts)
+}
+
+// OpenDisk creates a disk backed by the image file at path. Any partial sector at the end of the file is ignored
+func OpenDisk(path string, interrupts *InterruptController) (*DiskDevice, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return NewDisk(f, uint32(info.Size()/diskSectorBytes), interrupts), nil
+}
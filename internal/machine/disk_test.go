@@ -0,0 +1,109 @@
+package machine
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskDevice(t *testing.T) {
+	t.Run("write then read a sector", func(t *testing.T) {
+		mem := NewMemory()
+		disk := NewMemoryDisk(4, nil)
+		bus := newBus(t, mem, disk)
+		for i := uint32(0); i < DISK_SECTOR_SIZE; i++ {
+			bus.Write(0x200+i, i*3)
+		}
+		assert.NoError(t, bus.Write(DISK_SECTOR, 2))
+		assert.NoError(t, bus.Write(DISK_BUFFER, 0x200))
+		assert.NoError(t, bus.Write(DISK_COMMAND, DISK_WRITE))
+		status, _ := bus.Read(DISK_STATUS)
+		assert.Equal(t, DISK_DONE, status)
+
+		assert.NoError(t, bus.Write(DISK_STATUS, DISK_DONE))
+		assert.NoError(t, bus.Write(DISK_BUFFER, 0x400))
+		assert.NoError(t, bus.Write(DISK_COMMAND, DISK_READ))
+		status, _ = bus.Read(DISK_STATUS)
+		assert.Equal(t, DISK_DONE, status)
+		for i := uint32(0); i < DISK_SECTOR_SIZE; i++ {
+			word, _ := mem.Read(0x400 + i)
+			assert.Equal(t, i*3, word)
+		}
+	})
+	t.Run("other sectors are untouched", func(t *testing.T) {
+		mem := NewMemory()
+		disk := NewMemoryDisk(2, nil)
+		bus := newBus(t, mem, disk)
+		bus.Write(0x200, 0xDEADBEEF)
+		bus.Write(DISK_BUFFER, 0x200)
+		bus.Write(DISK_COMMAND, DISK_WRITE)
+
+		bus.Write(DISK_SECTOR, 1)
+		bus.Write(DISK_COMMAND, DISK_READ)
+		word, _ := mem.Read(0x200)
+		assert.Equal(t, uint32(0), word)
+	})
+	t.Run("sector past the end", func(t *testing.T) {
+		disk := NewMemoryDisk(2, nil)
+		bus := newBus(t, NewMemory(), disk)
+		bus.Write(DISK_SECTOR, 2)
+		assert.NoError(t, bus.Write(DISK_COMMAND, DISK_READ))
+		status, _ := bus.Read(DISK_STATUS)
+		assert.Equal(t, DISK_DONE|DISK_ERROR, status)
+	})
+	t.Run("buffer over the disk registers", func(t *testing.T) {
+		disk := NewMemoryDisk(2, nil)
+		bus := newBus(t, NewMemory(), disk)
+		bus.Write(DISK_BUFFER, 0xFFA0)
+		assert.NoError(t, bus.Write(DISK_COMMAND, DISK_READ))
+		status, _ := bus.Read(DISK_STATUS)
+		assert.Equal(t, DISK_DONE|DISK_ERROR, status)
+	})
+	t.Run("unknown command", func(t *testing.T) {
+		disk := NewMemoryDisk(2, nil)
+		newBus(t, NewMemory(), disk)
+		assert.Error(t, disk.Write(DISK_COMMAND, 0xFF))
+	})
+	t.Run("raises interrupt", func(t *testing.T) {
+		ic := NewInterruptController()
+		disk := NewMemoryDisk(1, ic)
+		newBus(t, NewMemory(), disk)
+		disk.Write(DISK_COMMAND, DISK_READ)
+		pending, _ := ic.Read(INTERRUPT_PENDING)
+		assert.Equal(t, uint32(1)<<IRQ_DISK, pending)
+	})
+	t.Run("image file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "disk.img")
+		image := make([]byte, diskSectorBytes*2+10)
+		image[diskSectorBytes] = 0x78
+		image[diskSectorBytes+1] = 0x56
+		image[diskSectorBytes+2] = 0x34
+		image[diskSectorBytes+3] = 0x12
+		if !assert.NoError(t, os.WriteFile(path, image, 0600)) {
+			return
+		}
+		disk, err := OpenDisk(path, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, uint32(2), disk.Sectors())
+		mem := NewMemory()
+		bus := newBus(t, mem, disk)
+		bus.Write(DISK_SECTOR, 1)
+		bus.Write(DISK_BUFFER, 0x300)
+		bus.Write(DISK_COMMAND, DISK_READ)
+		word, _ := mem.Read(0x300)
+		assert.Equal(t, uint32(0x12345678), word)
+
+		mem.Write(0x300, 0xCAFEF00D)
+		bus.Write(DISK_SECTOR, 0)
+		bus.Write(DISK_COMMAND, DISK_WRITE)
+		assert.NoError(t, disk.Close())
+		written, err := os.ReadFile(path)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, []byte{0x0D, 0xF0, 0xFE, 0xCA}, written[:4])
+	})
+}
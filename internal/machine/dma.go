@@ -0,0 +1,170 @@
+package machine
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	DMA_SOURCE = uint32(0xFFEF) + iota
+	DMA_DEST
+	DMA_LENGTH
+	DMA_CONTROL
+	DMA_STATUS
+)
+
+// Bits in DMA_CONTROL
+const (
+	// DMA_START begins a transfer. Clearing it stops the transfer where it is
+	DMA_START uint32 = 1 << iota
+	// DMA_INTERRUPT raises IRQ_DMA when a transfer finishes
+	DMA_INTERRUPT
+)
+
+// DMA_RATE_SHIFT is the position of the words per tick in DMA_CONTROL. A rate of 0 copies one word per tick
+const DMA_RATE_SHIFT = 16
+
+// Bits in DMA_STATUS
+const (
+	// DMA_BUSY is set while a transfer is running
+	DMA_BUSY uint32 = 1 << iota
+	// DMA_DONE is set when a transfer finishes
+	DMA_DONE
+	// DMA_ERROR is set when a transfer stops because an address could not be read or written
+	DMA_ERROR
+)
+
+// DMAController is a bus device that copies blocks of words from one address to another while the CPU carries on
+type DMAController struct {
+	lock       sync.Mutex
+	source     uint32
+	dest       uint32
+	length     uint32
+	control    uint32
+	status     uint32
+	bus        *Bus
+	interrupts *InterruptController
+}
+
+func (d *DMAController) MemoryRange() *MemoryRange {
+	// Addresses:
+	// * 0xFFEF - Address to copy from, counts up as words are copied
+	// * 0xFFF0 - Address to copy to, counts up as words are copied
+	// * 0xFFF1 - Words left to copy
+	// * 0xFFF2 - Control bits, the top 16 bits are the number of words to copy each tick
+	// * 0xFFF3 - Status bits, writing a 1 to a bit clears it
+	return &MemoryRange{
+		Start: 0xFFEF,
+		End:   0xFFF3,
+	}
+}
+
+func (d *DMAController) Read(address uint32) (uint32, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	switch address {
+	case DMA_SOURCE:
+		return d.source, nil
+	case DMA_DEST:
+		return d.dest, nil
+	case DMA_LENGTH:
+		return d.length, nil
+	case DMA_CONTROL:
+		return d.control, nil
+	case DMA_STATUS:
+		return d.status, nil
+	}
+	return 0, nil
+}
+
+func (d *DMAController) Write(address, value uint32) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	switch address {
+	case DMA_SOURCE:
+		d.source = value
+	case DMA_DEST:
+		d.dest = value
+	case DMA_LENGTH:
+		d.length = value
+	case DMA_CONTROL:
+		d.control = value
+		if value&DMA_START > 0 {
+			d.status = d.status | DMA_BUSY
+		} else {
+			d.status = d.status &^ DMA_BUSY
+		}
+	case DMA_STATUS:
+		// Only the CPU can start and stop a transfer
+		d.status = d.status &^ (value &^ DMA_BUSY)
+	}
+	return nil
+}
+
+func (d *DMAController) ConnectBus(b *Bus) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.bus = b
+}
+
+// Tick copies the next few words of a running transfer
+func (d *DMAController) Tick() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.status&DMA_BUSY == 0 {
+		return
+	}
+	rate := d.control >> DMA_RATE_SHIFT
+	if rate == 0 {
+		rate = 1
+	}
+	for i := uint32(0); i < rate && d.length > 0; i++ {
+		if err := d.copyWord(); err != nil {
+			d.finish(DMA_ERROR)
+			return
+		}
+	}
+	if d.length == 0 {
+		d.finish(DMA_DONE)
+	}
+}
+
+func (d *DMAController) copyWord() error {
+	if d.bus == nil {
+		return fmt.Errorf("dma controller is not connected to a bus")
+	}
+	// The controller can't copy to or from its own registers
+	for _, address := range [2]uint32{d.source, d.dest} {
+		if DMA_SOURCE <= address && address <= DMA_STATUS {
+			return fmt.Errorf("dma transfer touches the controller at %x", address)
+		}
+	}
+	word, err := d.bus.Read(d.source)
+	if err != nil {
+		return err
+	}
+	err = d.bus.Write(d.dest, word)
+	if err != nil {
+		return err
+	}
+	d.source++
+	d.dest++
+	d.length--
+	return nil
+}
+
+// finish stops the transfer and sets status, along with DMA_DONE
+func (d *DMAController) finish(status uint32) {
+	d.control = d.control &^ DMA_START
+	d.status = d.status&^DMA_BUSY | status | DMA_DONE
+	if d.control&DMA_INTERRUPT > 0 && d.interrupts != nil {
+		d.interrupts.Raise(IRQ_DMA)
+	}
+}
+
+// NewDMAController creates an idle DMA controller. interrupts may be nil if it should never raise IRQ_DMA
+func NewDMAController(interrupts *InterruptController) *DMAController {
+	return &DMAController{
+		interrupts: interrupts,
+	}
+}
@@ -0,0 +1,115 @@
+package machine
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDMAController(t *testing.T) {
+	setup := func(ic *InterruptController) (*Memory, *DMAController, *Bus) {
+		mem := NewMemory()
+		dma := NewDMAController(ic)
+		bus := newBus(t, mem, dma)
+		for i := uint32(0); i < 5; i++ {
+			mem.Write(0x200+i, i+1)
+		}
+		bus.Write(DMA_SOURCE, 0x200)
+		bus.Write(DMA_DEST, 0x300)
+		bus.Write(DMA_LENGTH, 5)
+		return mem, dma, bus
+	}
+	t.Run("copies one word a tick", func(t *testing.T) {
+		mem, dma, bus := setup(nil)
+		bus.Write(DMA_CONTROL, DMA_START)
+		for i := uint32(0); i < 4; i++ {
+			dma.Tick()
+			length, _ := bus.Read(DMA_LENGTH)
+			assert.Equal(t, 4-i, length)
+			status, _ := bus.Read(DMA_STATUS)
+			assert.Equal(t, DMA_BUSY, status)
+		}
+		dma.Tick()
+		status, _ := bus.Read(DMA_STATUS)
+		assert.Equal(t, DMA_DONE, status)
+		control, _ := bus.Read(DMA_CONTROL)
+		assert.Equal(t, uint32(0), control)
+		for i := uint32(0); i < 5; i++ {
+			word, _ := mem.Read(0x300 + i)
+			assert.Equal(t, i+1, word)
+		}
+		word, _ := mem.Read(0x305)
+		assert.Equal(t, uint32(0), word)
+	})
+	t.Run("configurable rate", func(t *testing.T) {
+		mem, dma, bus := setup(nil)
+		bus.Write(DMA_CONTROL, DMA_START|2<<DMA_RATE_SHIFT)
+		dma.Tick()
+		dma.Tick()
+		status, _ := bus.Read(DMA_STATUS)
+		assert.Equal(t, DMA_BUSY, status)
+		word, _ := mem.Read(0x303)
+		assert.Equal(t, uint32(4), word)
+		dma.Tick()
+		status, _ = bus.Read(DMA_STATUS)
+		assert.Equal(t, DMA_DONE, status)
+	})
+	t.Run("raises interrupt", func(t *testing.T) {
+		ic := NewInterruptController()
+		_, dma, bus := setup(ic)
+		bus.Write(DMA_CONTROL, DMA_START|DMA_INTERRUPT|8<<DMA_RATE_SHIFT)
+		dma.Tick()
+		pending, _ := ic.Read(INTERRUPT_PENDING)
+		assert.Equal(t, uint32(1)<<IRQ_DMA, pending)
+	})
+	t.Run("stop a transfer", func(t *testing.T) {
+		mem, dma, bus := setup(nil)
+		bus.Write(DMA_CONTROL, DMA_START)
+		dma.Tick()
+		bus.Write(DMA_CONTROL, 0)
+		dma.Tick()
+		length, _ := bus.Read(DMA_LENGTH)
+		assert.Equal(t, uint32(4), length)
+		status, _ := bus.Read(DMA_STATUS)
+		assert.Equal(t, uint32(0), status)
+		word, _ := mem.Read(0x301)
+		assert.Equal(t, uint32(0), word)
+	})
+	t.Run("unmapped address", func(t *testing.T) {
+		_, dma, bus := setup(nil)
+		bus.Write(DMA_DEST, 0xFFE1)
+		bus.Write(DMA_CONTROL, DMA_START)
+		dma.Tick()
+		status, _ := bus.Read(DMA_STATUS)
+		assert.Equal(t, DMA_DONE|DMA_ERROR, status)
+		bus.Write(DMA_STATUS, DMA_DONE|DMA_ERROR)
+		status, _ = bus.Read(DMA_STATUS)
+		assert.Equal(t, uint32(0), status)
+	})
+	t.Run("own registers", func(t *testing.T) {
+		_, dma, bus := setup(nil)
+		bus.Write(DMA_SOURCE, DMA_LENGTH)
+		bus.Write(DMA_CONTROL, DMA_START)
+		dma.Tick()
+		status, _ := bus.Read(DMA_STATUS)
+		assert.Equal(t, DMA_DONE|DMA_ERROR, status)
+	})
+	t.Run("runs alongside the cpu", func(t *testing.T) {
+		mem, _, bus := setup(nil)
+		registers := NewRegisterBank()
+		cpu := NewCPU(registers, bus)
+		// Start the transfer, then spin until it's done
+		bus.Write(0x100, 0x03F00001)
+		bus.Write(0x101, 0x020FFFF2)
+		bus.Write(0x102, 0x01F0FFF3)
+		bus.Write(0x103, 0x11F00002)
+		bus.Write(0x104, 0x0CF00106)
+		bus.Write(0x105, 0x0CF00102)
+		bus.Write(0x106, 0x00000000)
+		for i := 0; i < 40 && registers.registerMap[SR].Value&STATUS_HALT == 0; i++ {
+			assert.NoError(t, cpu.Tick())
+		}
+		assert.Equal(t, STATUS_HALT, registers.registerMap[SR].Value&STATUS_HALT)
+		word, _ := mem.Read(0x304)
+		assert.Equal(t, uint32(5), word)
+	})
+}
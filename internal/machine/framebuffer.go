@@ -0,0 +1,118 @@
+package machine
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"sync"
+)
+
+// Size of the screen in pixels
+const (
+	FRAMEBUFFER_WIDTH  = 320
+	FRAMEBUFFER_HEIGHT = 200
+)
+
+const (
+	// FRAMEBUFFER is the address of the top left pixel. Pixels are stored a row at a time
+	FRAMEBUFFER = uint32(0x10000)
+	// FRAMEBUFFER_CONTROL comes straight after the last pixel
+	FRAMEBUFFER_CONTROL = FRAMEBUFFER + FRAMEBUFFER_WIDTH*FRAMEBUFFER_HEIGHT
+)
+
+// Commands written to FRAMEBUFFER_CONTROL
+const (
+	// FRAMEBUFFER_SNAPSHOT asks the host to save a picture of the screen
+	FRAMEBUFFER_SNAPSHOT = uint32(iota + 1)
+	// FRAMEBUFFER_CLEAR sets every pixel to black
+	FRAMEBUFFER_CLEAR
+)
+
+// FramebufferDevice is a bus device holding a screen of pixels, each one word in the form 0x00RRGGBB
+type FramebufferDevice struct {
+	// The host may take a picture while the machine is running, so guard the pixels
+	lock       sync.Mutex
+	pixels     [FRAMEBUFFER_WIDTH * FRAMEBUFFER_HEIGHT]uint32
+	onSnapshot func(image.Image) error
+}
+
+func (f *FramebufferDevice) MemoryRange() *MemoryRange {
+	// Addresses:
+	// * 0x10000 - 0x1F9FF - Pixels
+	// * 0x1FA00 - Write a control command
+	return &MemoryRange{
+		Start: FRAMEBUFFER,
+		End:   FRAMEBUFFER_CONTROL,
+	}
+}
+
+func (f *FramebufferDevice) Read(address uint32) (uint32, error) {
+	if address < FRAMEBUFFER || address >= FRAMEBUFFER_CONTROL {
+		return 0, nil
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.pixels[address-FRAMEBUFFER], nil
+}
+
+func (f *FramebufferDevice) Write(address, value uint32) error {
+	if address == FRAMEBUFFER_CONTROL {
+		return f.control(value)
+	}
+	if address < FRAMEBUFFER || address > FRAMEBUFFER_CONTROL {
+		return fmt.Errorf("address %x out of range", address)
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.pixels[address-FRAMEBUFFER] = value
+	return nil
+}
+
+func (f *FramebufferDevice) control(command uint32) error {
+	switch command {
+	case FRAMEBUFFER_SNAPSHOT:
+		if f.onSnapshot == nil {
+			return nil
+		}
+		return f.onSnapshot(f.Image())
+	case FRAMEBUFFER_CLEAR:
+		f.lock.Lock()
+		defer f.lock.Unlock()
+		f.pixels = [FRAMEBUFFER_WIDTH * FRAMEBUFFER_HEIGHT]uint32{}
+		return nil
+	}
+	return fmt.Errorf("unknown framebuffer command %d", command)
+}
+
+// OnSnapshot sets the function called with a picture of the screen when the program asks for a snapshot
+func (f *FramebufferDevice) OnSnapshot(fn func(image.Image) error) {
+	f.onSnapshot = fn
+}
+
+// Image takes a picture of the screen as it is now
+func (f *FramebufferDevice) Image() *image.RGBA {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	img := image.NewRGBA(image.Rect(0, 0, FRAMEBUFFER_WIDTH, FRAMEBUFFER_HEIGHT))
+	for i, pixel := range f.pixels {
+		img.SetRGBA(i%FRAMEBUFFER_WIDTH, i/FRAMEBUFFER_WIDTH, color.RGBA{
+			R: uint8(pixel >> 16),
+			G: uint8(pixel >> 8),
+			B: uint8(pixel),
+			A: 0xFF,
+		})
+	}
+	return img
+}
+
+// WritePNG writes a picture of the screen to w as a PNG
+func (f *FramebufferDevice) WritePNG(w io.Writer) error {
+	return png.Encode(w, f.Image())
+}
+
+// NewFramebuffer creates a black screen
+func NewFramebuffer() *FramebufferDevice {
+	return &FramebufferDevice{}
+}
@@ -0,0 +1,89 @@
+package machine
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestFramebufferDevice(t *testing.T) {
+	t.Run("pixels", func(t *testing.T) {
+		fb := NewFramebuffer()
+		bus := newBus(t, NewMemory(), fb)
+		assert.NoError(t, bus.Write(FRAMEBUFFER, 0x00FF0000))
+		assert.NoError(t, bus.Write(FRAMEBUFFER+FRAMEBUFFER_WIDTH+2, 0x0000FF80))
+		pixel, err := bus.Read(FRAMEBUFFER + FRAMEBUFFER_WIDTH + 2)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x0000FF80), pixel)
+
+		img := fb.Image()
+		assert.Equal(t, color.RGBA{R: 0xFF, A: 0xFF}, img.At(0, 0))
+		assert.Equal(t, color.RGBA{G: 0xFF, B: 0x80, A: 0xFF}, img.At(2, 1))
+		assert.Equal(t, color.RGBA{A: 0xFF}, img.At(FRAMEBUFFER_WIDTH-1, FRAMEBUFFER_HEIGHT-1))
+	})
+	t.Run("png", func(t *testing.T) {
+		fb := NewFramebuffer()
+		fb.Write(FRAMEBUFFER_CONTROL-1, 0x00123456)
+		out := &bytes.Buffer{}
+		if !assert.NoError(t, fb.WritePNG(out)) {
+			return
+		}
+		img, err := png.Decode(out)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, image.Rect(0, 0, FRAMEBUFFER_WIDTH, FRAMEBUFFER_HEIGHT), img.Bounds())
+		r, g, b, _ := img.At(FRAMEBUFFER_WIDTH-1, FRAMEBUFFER_HEIGHT-1).RGBA()
+		assert.Equal(t, []uint32{0x12, 0x34, 0x56}, []uint32{r >> 8, g >> 8, b >> 8})
+	})
+	t.Run("snapshot", func(t *testing.T) {
+		fb := NewFramebuffer()
+		var snapshot image.Image
+		fb.OnSnapshot(func(img image.Image) error {
+			snapshot = img
+			return nil
+		})
+		fb.Write(FRAMEBUFFER+1, 0x00FFFFFF)
+		assert.NoError(t, fb.Write(FRAMEBUFFER_CONTROL, FRAMEBUFFER_SNAPSHOT))
+		if !assert.NotNil(t, snapshot) {
+			return
+		}
+		assert.Equal(t, color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}, snapshot.At(1, 0))
+	})
+	t.Run("clear", func(t *testing.T) {
+		fb := NewFramebuffer()
+		fb.Write(FRAMEBUFFER+1, 0x00FFFFFF)
+		assert.NoError(t, fb.Write(FRAMEBUFFER_CONTROL, FRAMEBUFFER_CLEAR))
+		pixel, _ := fb.Read(FRAMEBUFFER + 1)
+		assert.Equal(t, uint32(0), pixel)
+	})
+	t.Run("unknown command", func(t *testing.T) {
+		fb := NewFramebuffer()
+		assert.Error(t, fb.Write(FRAMEBUFFER_CONTROL, 0xFF))
+	})
+	t.Run("program draws a line", func(t *testing.T) {
+		fb := NewFramebuffer()
+		bus := newBus(t, NewMemory(), fb)
+		registers := NewRegisterBank()
+		cpu := NewCPU(registers, bus)
+		// R1 = colour, R0 walks along the top row with STOREINC
+		bus.Write(0x100, 0x83F00000)
+		bus.Write(0x101, FRAMEBUFFER)
+		bus.Write(0x102, 0x83F10000)
+		bus.Write(0x103, 0x00ABCDEF)
+		for i := uint32(0); i < 4; i++ {
+			bus.Write(0x104+i, 0x2A100000)
+		}
+		for i := 0; i < 6; i++ {
+			assert.NoError(t, cpu.Tick())
+		}
+		img := fb.Image()
+		for x := 0; x < 4; x++ {
+			assert.Equal(t, color.RGBA{R: 0xAB, G: 0xCD, B: 0xEF, A: 0xFF}, img.At(x, 0))
+		}
+		assert.Equal(t, color.RGBA{A: 0xFF}, img.At(4, 0))
+	})
+}
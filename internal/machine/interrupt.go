@@ -0,0 +1,178 @@
+package machine
+
+import "sync"
+
+// INTERRUPT_LINES is the number of hardware interrupt lines on the controller
+const INTERRUPT_LINES = 16
+
+// Interrupt lines raised by the built in devices
+const (
+	IRQ_TIMER = uint32(iota)
+	IRQ_TERMINAL
+	IRQ_DISK
+	IRQ_DMA
+	IRQ_UART
+)
+
+// EXCEPTION_VECTORS is the offset of the first exception handler in the vector table. The handler for a fault
+// lives at EXCEPTION_VECTORS plus its cause
+const EXCEPTION_VECTORS = INTERRUPT_LINES
+
+// Causes of faults raised by the CPU
+const (
+	FAULT_NONE = uint32(iota)
+	FAULT_MEMORY
+	FAULT_DIVIDE_BY_ZERO
+	FAULT_ILLEGAL_INSTRUCTION
+	FAULT_STACK_OVERFLOW
+	FAULT_SYSCALL
+)
+
+const (
+	INTERRUPT_PENDING = uint32(0xFFF8) + iota
+	INTERRUPT_MASK
+	INTERRUPT_RAISE
+	INTERRUPT_VECTORS
+	INTERRUPT_FAULT_CAUSE
+	INTERRUPT_FAULT_PC
+	INTERRUPT_STACK_LIMIT
+	__interrupt_reserved1
+)
+
+// InterruptController is a bus device that collects interrupt requests from other devices
+// and hands them to the CPU one at a time
+type InterruptController struct {
+	// Devices may raise lines from their own goroutines, so guard the registers
+	lock       sync.Mutex
+	pending    uint32
+	mask       uint32
+	vectorBase uint32
+	faultCause uint32
+	faultPC    uint32
+	// SP may not be pushed below this address
+	stackBottom uint32
+}
+
+func (ic *InterruptController) MemoryRange() *MemoryRange {
+	// Addresses:
+	// * 0xFFF8 - Pending lines, writing a 1 to a bit clears it
+	// * 0xFFF9 - Mask of enabled lines
+	// * 0xFFFA - Write a line number to raise it from software
+	// * 0xFFFB - Address of the vector table
+	// * 0xFFFC - Cause of the last fault
+	// * 0xFFFD - Address of the instruction that caused the last fault
+	// * 0xFFFE - Stack limit, pushing when SP is at or below this is a stack overflow
+	// * 0xFFFF - reserved
+	return &MemoryRange{
+		Start: 0xFFF8,
+		End:   0xFFFF,
+	}
+}
+
+func (ic *InterruptController) Read(address uint32) (uint32, error) {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+	switch address {
+	case INTERRUPT_PENDING:
+		return ic.pending, nil
+	case INTERRUPT_MASK:
+		return ic.mask, nil
+	case INTERRUPT_VECTORS:
+		return ic.vectorBase, nil
+	case INTERRUPT_FAULT_CAUSE:
+		return ic.faultCause, nil
+	case INTERRUPT_FAULT_PC:
+		return ic.faultPC, nil
+	case INTERRUPT_STACK_LIMIT:
+		return ic.stackBottom, nil
+	}
+	return 0, nil
+}
+
+func (ic *InterruptController) Write(address, value uint32) error {
+	switch address {
+	case INTERRUPT_PENDING:
+		ic.lock.Lock()
+		ic.pending = ic.pending &^ value
+		ic.lock.Unlock()
+	case INTERRUPT_MASK:
+		ic.lock.Lock()
+		ic.mask = value & (1<<INTERRUPT_LINES - 1)
+		ic.lock.Unlock()
+	case INTERRUPT_RAISE:
+		ic.Raise(value)
+	case INTERRUPT_VECTORS:
+		ic.lock.Lock()
+		ic.vectorBase = value
+		ic.lock.Unlock()
+	case INTERRUPT_FAULT_CAUSE:
+		ic.lock.Lock()
+		ic.faultCause = value
+		ic.lock.Unlock()
+	case INTERRUPT_FAULT_PC:
+		ic.lock.Lock()
+		ic.faultPC = value
+		ic.lock.Unlock()
+	case INTERRUPT_STACK_LIMIT:
+		ic.lock.Lock()
+		ic.stackBottom = value
+		ic.lock.Unlock()
+	}
+	return nil
+}
+
+// Raise marks an interrupt line as pending. Lines outside the controller's range are ignored
+func (ic *InterruptController) Raise(line uint32) {
+	if line >= INTERRUPT_LINES {
+		return
+	}
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+	ic.pending = ic.pending | 1<<line
+}
+
+// next takes the lowest numbered line that is both pending and enabled, returning the
+// address of its entry in the vector table
+func (ic *InterruptController) next() (uint32, bool) {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+	active := ic.pending & ic.mask
+	if active == 0 {
+		return 0, false
+	}
+	for line := uint32(0); line < INTERRUPT_LINES; line++ {
+		if active&(1<<line) > 0 {
+			ic.pending = ic.pending &^ (1 << line)
+			return ic.vectorBase + line, true
+		}
+	}
+	return 0, false
+}
+
+// exceptionVector gives the address of the vector table entry for a fault
+func (ic *InterruptController) exceptionVector(cause uint32) uint32 {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+	return ic.vectorBase + EXCEPTION_VECTORS + cause
+}
+
+// recordFault saves the cause and address of a fault for its handler to inspect
+func (ic *InterruptController) recordFault(cause, pc uint32) {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+	ic.faultCause = cause
+	ic.faultPC = pc
+}
+
+func (ic *InterruptController) stackLimit() uint32 {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+	return ic.stackBottom
+}
+
+func NewInterruptController() *InterruptController {
+	return &InterruptController{
+		mask:       1<<INTERRUPT_LINES - 1,
+		vectorBase: 0x0000,
+	}
+}
@@ -0,0 +1,53 @@
+package machine
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInterruptController(t *testing.T) {
+	t.Run("raise sets pending bit", func(t *testing.T) {
+		ic := NewInterruptController()
+		ic.Raise(3)
+		got, err := ic.Read(INTERRUPT_PENDING)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x08), got)
+	})
+	t.Run("raise from software", func(t *testing.T) {
+		ic := NewInterruptController()
+		err := ic.Write(INTERRUPT_RAISE, 0x02)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x04), ic.pending)
+	})
+	t.Run("invalid line ignored", func(t *testing.T) {
+		ic := NewInterruptController()
+		ic.Raise(INTERRUPT_LINES)
+		assert.Equal(t, uint32(0x00), ic.pending)
+	})
+	t.Run("writing pending clears bits", func(t *testing.T) {
+		ic := NewInterruptController()
+		ic.Raise(0)
+		ic.Raise(1)
+		err := ic.Write(INTERRUPT_PENDING, 0x01)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x02), ic.pending)
+	})
+	t.Run("next takes lowest enabled line", func(t *testing.T) {
+		ic := NewInterruptController()
+		ic.Write(INTERRUPT_VECTORS, 0x20)
+		ic.Write(INTERRUPT_MASK, 0x0C)
+		ic.Raise(1)
+		ic.Raise(3)
+		ic.Raise(2)
+
+		vector, ok := ic.next()
+		assert.True(t, ok)
+		assert.Equal(t, uint32(0x22), vector)
+		vector, ok = ic.next()
+		assert.True(t, ok)
+		assert.Equal(t, uint32(0x23), vector)
+		_, ok = ic.next()
+		assert.False(t, ok)
+		assert.Equal(t, uint32(0x02), ic.pending)
+	})
+}
@@ -1,6 +1,13 @@
 package machine
 
 // NewMachine creates a new, default machine
-func NewMachine() *CPU {
-	return NewCPU(NewRegisterBank(), NewBus(NewMemory()))
+func NewMachine() (*CPU, error) {
+	interrupts := NewInterruptController()
+	bus, err := NewBus(NewMemory(), interrupts, NewTimer(interrupts))
+	if err != nil {
+		return nil, err
+	}
+	cpu := NewCPU(NewRegisterBank(), bus)
+	cpu.AttachInterruptController(interrupts)
+	return cpu, nil
 }
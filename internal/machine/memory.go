@@ -8,8 +8,17 @@ import (
 const maxMemorySize = 0xFFE1
 const maxMemoryAddress = 0xFFE0
 
+// Protection bits for ranges of memory
+const (
+	MEMORY_READ    = uint8(executable.BLOCK_READ)
+	MEMORY_WRITE   = uint8(executable.BLOCK_WRITE)
+	MEMORY_EXECUTE = uint8(executable.BLOCK_EXECUTE)
+	MEMORY_ALL     = MEMORY_READ | MEMORY_WRITE | MEMORY_EXECUTE
+)
+
 type Memory struct {
-	mem [maxMemorySize]uint32
+	mem        [maxMemorySize]uint32
+	protection [maxMemorySize]uint8
 }
 
 func (m *Memory) MemoryRange() *MemoryRange {
@@ -23,6 +32,9 @@ func (m *Memory) Read(address uint32) (uint32, error) {
 	if address > 0xFFE0 {
 		return 0, fmt.Errorf("address %x out of range", address)
 	}
+	if m.protection[address]&MEMORY_READ == 0 {
+		return 0, fmt.Errorf("address %x is not readable", address)
+	}
 	return m.mem[address], nil
 }
 
@@ -30,10 +42,31 @@ func (m *Memory) Write(address, value uint32) error {
 	if address > 0xFFE0 {
 		return fmt.Errorf("address %x out of range", address)
 	}
+	if m.protection[address]&MEMORY_WRITE == 0 {
+		return fmt.Errorf("address %x is not writable", address)
+	}
 	m.mem[address] = value
 	return nil
 }
 
+func (m *Memory) Executable(address uint32) bool {
+	return address <= maxMemoryAddress && m.protection[address]&MEMORY_EXECUTE > 0
+}
+
+// Protect sets the protection bits of every address from start to end inclusive
+func (m *Memory) Protect(start, end uint32, protection uint8) error {
+	if start > end || end > maxMemoryAddress {
+		return fmt.Errorf("range %x-%x is not valid", start, end)
+	}
+	if protection&^MEMORY_ALL != 0 {
+		return fmt.Errorf("protection %x is not valid", protection)
+	}
+	for address := start; address <= end; address++ {
+		m.protection[address] = protection
+	}
+	return nil
+}
+
 func (m *Memory) Load(l *executable.LoadableFile) error {
 	for i := uint32(0); i < l.BlockCount; i++ {
 		b := l.Blocks[i]
@@ -43,12 +76,25 @@ func (m *Memory) Load(l *executable.LoadableFile) error {
 		for j := uint32(0); j < b.BlockSize; j++ {
 			m.mem[b.Address+j] = b.Words[j]
 		}
+		if l.Flags&executable.FLAG_BLOCK_PROTECTION > 0 && b.BlockSize > 0 {
+			if b.Protection > uint32(MEMORY_ALL) {
+				return fmt.Errorf("block %d has invalid protection %x", i, b.Protection)
+			}
+			err := m.Protect(b.Address, b.Address+b.BlockSize-1, uint8(b.Protection))
+			if err != nil {
+				return fmt.Errorf("could not protect block %d: %v", i, err)
+			}
+		}
 	}
 	return nil
 }
 
 func NewMemory() *Memory {
-	return &Memory{
+	m := &Memory{
 		mem: [maxMemorySize]uint32{},
 	}
+	for i := range m.protection {
+		m.protection[i] = MEMORY_ALL
+	}
+	return m
 }
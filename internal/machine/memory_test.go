@@ -73,3 +73,56 @@ func TestMemory_Load(t *testing.T) {
 		}
 	}
 }
+
+func TestMemory_Protect(t *testing.T) {
+	mem := NewMemory()
+	assert.True(t, mem.Executable(0x100))
+	assert.NoError(t, mem.Protect(0x100, 0x1FF, MEMORY_READ|MEMORY_EXECUTE))
+	assert.Error(t, mem.Write(0x100, 0x01))
+	assert.Error(t, mem.Write(0x1FF, 0x01))
+	assert.NoError(t, mem.Write(0x200, 0x01))
+	_, err := mem.Read(0x100)
+	assert.NoError(t, err)
+	assert.True(t, mem.Executable(0x100))
+
+	assert.NoError(t, mem.Protect(0x200, 0x200, 0))
+	_, err = mem.Read(0x200)
+	assert.Error(t, err)
+	assert.False(t, mem.Executable(0x200))
+
+	assert.Error(t, mem.Protect(0x200, 0x100, MEMORY_ALL))
+	assert.Error(t, mem.Protect(0x200, 0xFFFF, MEMORY_ALL))
+	assert.Error(t, mem.Protect(0x200, 0x200, 0x10))
+}
+
+func TestMemory_LoadProtected(t *testing.T) {
+	mem := NewMemory()
+	file := &executable.LoadableFile{
+		BlockCount: 2,
+		Flags:      executable.FLAG_BLOCK_PROTECTION,
+		Blocks: []*executable.MemoryBlock{
+			{
+				Address:    0x100,
+				BlockSize:  0x02,
+				Protection: executable.BLOCK_READ | executable.BLOCK_EXECUTE,
+				Words:      []uint32{0x1234, 0x5678},
+			},
+			{
+				Address:    0x102,
+				BlockSize:  0x01,
+				Protection: executable.BLOCK_READ | executable.BLOCK_WRITE,
+				Words:      []uint32{0x9ABC},
+			},
+		},
+	}
+	assert.NoError(t, mem.Load(file))
+	assert.Error(t, mem.Write(0x101, 0x00))
+	assert.True(t, mem.Executable(0x101))
+	assert.NoError(t, mem.Write(0x102, 0x00))
+	assert.False(t, mem.Executable(0x102))
+
+	t.Run("invalid protection", func(t *testing.T) {
+		file.Blocks[1].Protection = 0x100
+		assert.Error(t, NewMemory().Load(file))
+	})
+}
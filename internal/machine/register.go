@@ -7,13 +7,13 @@ const (
 	R1
 	R2
 	R3
-	__reserved1
-	__reserved2
-	__reserved3
-	__reserved4
-	__reserved5
-	__reserved6
-	__reserved7
+	R4
+	R5
+	R6
+	R7
+	R8
+	R9
+	R10
 	SP
 	SR
 	PC
@@ -21,12 +21,20 @@ const (
 	IMMEDIATE
 )
 
+// FP is the frame pointer used by ENTER and LEAVE. It shares its slot with R10
+const FP = R10
+
 const (
 	STATUS_HALT uint32 = 1 << iota
 	STATUS_OVERFLOW
 	STATUS_UNDERFLOW
 	STATUS_DIVIDE_BY_ZERO
 	STATUS_MEMORY_ERROR
+	STATUS_INTERRUPT_ENABLE
+	STATUS_CARRY
+	STATUS_ZERO
+	STATUS_NEGATIVE
+	STATUS_SIGNED_OVERFLOW
 )
 
 type Register struct {
@@ -47,14 +55,21 @@ func (r *RegisterBank) GetRegister(name uint8) (*Register, error) {
 func NewRegisterBank() *RegisterBank {
 	return &RegisterBank{
 		registerMap: map[uint8]*Register{
-			R0: {0x00},
-			R1: {0x00},
-			R2: {0x00},
-			R3: {0x00},
-			SP: {0xFFE0},
-			SR: {0x00},
-			PC: {0x100},
-			IR: {0x00},
+			R0:  {0x00},
+			R1:  {0x00},
+			R2:  {0x00},
+			R3:  {0x00},
+			R4:  {0x00},
+			R5:  {0x00},
+			R6:  {0x00},
+			R7:  {0x00},
+			R8:  {0x00},
+			R9:  {0x00},
+			R10: {0x00},
+			SP:  {0xFFE0},
+			SR:  {0x00},
+			PC:  {0x100},
+			IR:  {0x00},
 		},
 	}
 }
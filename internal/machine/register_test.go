@@ -14,6 +14,16 @@ func TestRegisterBank_GetRegister(t *testing.T) {
 		}
 		assert.True(t, r0 == rb.registerMap[R0])
 	})
+	t.Run("Get general purpose registers", func(t *testing.T) {
+		rb := NewRegisterBank()
+		for name := R0; name <= R10; name++ {
+			r, err := rb.GetRegister(name)
+			if !assert.NoError(t, err, "register %x", name) {
+				t.FailNow()
+			}
+			assert.Equal(t, uint32(0x00), r.Value)
+		}
+	})
 	t.Run("Get invalid register", func(t *testing.T) {
 		rb := NewRegisterBank()
 		r, err := rb.GetRegister(0xFF)
@@ -0,0 +1,49 @@
+package machine
+
+import (
+	"fmt"
+	"github.com/ThreeToes/blogvm/internal/executable"
+)
+
+// ROMDevice is a bus device holding words that can be read and run but never written
+type ROMDevice struct {
+	address uint32
+	words   []uint32
+}
+
+func (r *ROMDevice) MemoryRange() *MemoryRange {
+	// Addresses:
+	// * The block's address onwards - the block's words
+	return &MemoryRange{
+		Start: r.address,
+		End:   r.address + uint32(len(r.words)) - 1,
+	}
+}
+
+func (r *ROMDevice) Read(address uint32) (uint32, error) {
+	if address < r.address || address-r.address >= uint32(len(r.words)) {
+		return 0, fmt.Errorf("address %x out of range", address)
+	}
+	return r.words[address-r.address], nil
+}
+
+func (r *ROMDevice) Write(address, _ uint32) error {
+	return fmt.Errorf("address %x is read only", address)
+}
+
+func (r *ROMDevice) Executable(_ uint32) bool {
+	return true
+}
+
+// NewROM creates a ROM holding a copy of the words in block, at the block's address
+func NewROM(block *executable.MemoryBlock) (*ROMDevice, error) {
+	if block.BlockSize == 0 || uint32(len(block.Words)) < block.BlockSize {
+		return nil, fmt.Errorf("block at %x has %d of %d words", block.Address, len(block.Words), block.BlockSize)
+	}
+	words := make([]uint32, block.BlockSize)
+	copy(words, block.Words)
+	return &ROMDevice{
+		address: block.Address,
+		words:   words,
+	}, nil
+}
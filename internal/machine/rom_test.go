@@ -0,0 +1,55 @@
+package machine
+
+import (
+	"github.com/ThreeToes/blogvm/internal/executable"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestROMDevice(t *testing.T) {
+	t.Run("read only", func(t *testing.T) {
+		rom, err := NewROM(&executable.MemoryBlock{
+			Address:   0x20000,
+			BlockSize: 2,
+			Words:     []uint32{0x1234, 0x5678},
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, &MemoryRange{Start: 0x20000, End: 0x20001}, rom.MemoryRange())
+		word, err := rom.Read(0x20001)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0x5678), word)
+		assert.Error(t, rom.Write(0x20000, 0))
+		word, _ = rom.Read(0x20000)
+		assert.Equal(t, uint32(0x1234), word)
+	})
+	t.Run("empty block", func(t *testing.T) {
+		_, err := NewROM(&executable.MemoryBlock{Address: 0x20000})
+		assert.Error(t, err)
+	})
+	t.Run("short block", func(t *testing.T) {
+		_, err := NewROM(&executable.MemoryBlock{Address: 0x20000, BlockSize: 2, Words: []uint32{0x01}})
+		assert.Error(t, err)
+	})
+	t.Run("runs code", func(t *testing.T) {
+		// COPY 0x2A R0, WRITE R0 [0x20000], HALT
+		rom, err := NewROM(&executable.MemoryBlock{
+			Address:   0x20000,
+			BlockSize: 4,
+			Words:     []uint32{0x03F0002A, 0x820F0000, 0x00020000, 0x00000000},
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		registers := NewRegisterBank()
+		cpu := NewCPU(registers, newBus(t, rom, NewMemory()))
+		registers.registerMap[PC].Value = 0x20000
+		assert.NoError(t, cpu.Tick())
+		assert.Equal(t, uint32(0x2A), registers.registerMap[R0].Value)
+		assert.NoError(t, cpu.Tick())
+		assert.Equal(t, STATUS_MEMORY_ERROR, registers.registerMap[SR].Value&STATUS_MEMORY_ERROR)
+		word, _ := rom.Read(0x20000)
+		assert.Equal(t, uint32(0x03F0002A), word)
+	})
+}
@@ -2,13 +2,29 @@ package machine
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
-	"os"
+	"io"
+	"strings"
+	"sync"
 )
 
-// TerminalDevice is a bus device that backs directly onto a real terminal
+// terminalInputBuffer is the number of characters the terminal holds before it stops reading input
+const terminalInputBuffer = 256
+
+// TerminalDevice is a bus device that prints to a writer and takes keyboard input from a reader
 type TerminalDevice struct {
-	consoleReader *bufio.Reader
+	output io.Writer
+	// Cursor position, counted from 0 in the top left
+	x     uint32
+	y     uint32
+	input chan rune
+	// done is closed to stop the input goroutine
+	done      chan struct{}
+	closeOnce sync.Once
+	// The input goroutine raises interrupts, so guard the controller
+	lock       sync.Mutex
+	interrupts *InterruptController
 }
 
 const (
@@ -16,7 +32,21 @@ const (
 	TERMINAL_INT
 	TERMINAL_X
 	TERMINAL_Y
-	__terminal_reserved1
+	TERMINAL_STATUS
+	TERMINAL_CONTROL
+	TERMINAL_COLOUR
+)
+
+// TERMINAL_INPUT_READY is set in TERMINAL_STATUS while there is a character waiting to be read
+const TERMINAL_INPUT_READY uint32 = 1
+
+// Commands written to TERMINAL_CONTROL
+const (
+	TERMINAL_CLEAR_SCREEN = uint32(iota + 1)
+	TERMINAL_CLEAR_LINE
+	TERMINAL_HIDE_CURSOR
+	TERMINAL_SHOW_CURSOR
+	TERMINAL_RESET_COLOUR
 )
 
 func (t *TerminalDevice) MemoryRange() *MemoryRange {
@@ -25,31 +55,168 @@ func (t *TerminalDevice) MemoryRange() *MemoryRange {
 	// * 0xFFE2 - Write a number to the terminal
 	// * 0xFFE3 - Cursor X position
 	// * 0xFFE4 - Cursor Y position
-	// * 0xFFE5 - reserved
+	// * 0xFFE5 - Status bits
+	// * 0xFFE6 - Write a control command
+	// * 0xFFE7 - Colour, the low 4 bits are the foreground and the next 4 the background
 	return &MemoryRange{
 		Start: 0xFFE1,
-		End:   0xFFE5,
+		End:   0xFFE7,
 	}
 }
 
 func (t *TerminalDevice) Read(address uint32) (uint32, error) {
-	// By default, Go doesn't provide a way to get unbuffered input from the console.
-	// Will leave this to the UI when I get to that
+	switch address {
+	case TERMINAL:
+		// Don't block the machine waiting for a key, programs should check TERMINAL_STATUS first
+		select {
+		case ch := <-t.input:
+			return uint32(ch), nil
+		default:
+			return 0, nil
+		}
+	case TERMINAL_X:
+		return t.x, nil
+	case TERMINAL_Y:
+		return t.y, nil
+	case TERMINAL_STATUS:
+		if len(t.input) > 0 {
+			return TERMINAL_INPUT_READY, nil
+		}
+	}
 	return 0, nil
 }
 
 func (t *TerminalDevice) Write(address, value uint32) error {
 	switch address {
 	case TERMINAL:
-		fmt.Printf("%c", rune(value))
+		t.advance(rune(value))
+		return t.print(string(rune(value)))
 	case TERMINAL_INT:
-		fmt.Printf("%d", value)
+		out := fmt.Sprintf("%d", value)
+		t.x += uint32(len(out))
+		return t.print(out)
+	case TERMINAL_X:
+		t.x = value
+		return t.moveCursor()
+	case TERMINAL_Y:
+		t.y = value
+		return t.moveCursor()
+	case TERMINAL_CONTROL:
+		return t.control(value)
+	case TERMINAL_COLOUR:
+		return t.print(fmt.Sprintf("\x1b[%d;%dm", ansiColour(value&0xF, 30), ansiColour(value>>4&0xF, 40)))
 	}
 	return nil
 }
 
-func NewTerminal() *TerminalDevice {
-	return &TerminalDevice{
-		consoleReader: bufio.NewReader(os.Stdin),
+func (t *TerminalDevice) print(s string) error {
+	_, err := io.WriteString(t.output, s)
+	return err
+}
+
+// advance keeps track of where the cursor ends up after printing ch
+func (t *TerminalDevice) advance(ch rune) {
+	switch ch {
+	case '\n':
+		t.x = 0
+		t.y++
+	case '\r':
+		t.x = 0
+	default:
+		t.x++
+	}
+}
+
+func (t *TerminalDevice) moveCursor() error {
+	// ANSI counts rows and columns from 1
+	return t.print(fmt.Sprintf("\x1b[%d;%dH", t.y+1, t.x+1))
+}
+
+func (t *TerminalDevice) control(command uint32) error {
+	switch command {
+	case TERMINAL_CLEAR_SCREEN:
+		t.x = 0
+		t.y = 0
+		return t.print("\x1b[2J\x1b[H")
+	case TERMINAL_CLEAR_LINE:
+		t.x = 0
+		return t.print("\x1b[2K\r")
+	case TERMINAL_HIDE_CURSOR:
+		return t.print("\x1b[?25l")
+	case TERMINAL_SHOW_CURSOR:
+		return t.print("\x1b[?25h")
+	case TERMINAL_RESET_COLOUR:
+		return t.print("\x1b[0m")
 	}
+	return fmt.Errorf("unknown terminal command %d", command)
+}
+
+// ansiColour turns a colour from 0-15 into an SGR parameter. Colours 0-7 are the normal colours and 8-15 their
+// bright versions. base is 30 for foreground colours and 40 for background
+func ansiColour(colour uint32, base int) int {
+	if colour < 8 {
+		return base + int(colour)
+	}
+	return base + 60 + int(colour-8)
+}
+
+// AttachInterruptController makes the terminal raise IRQ_TERMINAL on ic whenever a character arrives
+func (t *TerminalDevice) AttachInterruptController(ic *InterruptController) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.interrupts = ic
+}
+
+// Close stops the terminal taking input. A read from the console that is already waiting can't be interrupted,
+// so the input goroutine stops once it returns
+func (t *TerminalDevice) Close() error {
+	t.closeOnce.Do(func() {
+		close(t.done)
+	})
+	return nil
+}
+
+// readInput moves characters from in to the input buffer until in runs out or the terminal is closed
+func (t *TerminalDevice) readInput(in io.Reader) {
+	reader := bufio.NewReader(in)
+	for {
+		ch, _, err := reader.ReadRune()
+		if err != nil {
+			return
+		}
+		select {
+		case <-t.done:
+			return
+		default:
+		}
+		select {
+		case t.input <- ch:
+		case <-t.done:
+			return
+		}
+		t.lock.Lock()
+		if t.interrupts != nil {
+			t.interrupts.Raise(IRQ_TERMINAL)
+		}
+		t.lock.Unlock()
+	}
+}
+
+// NewTerminal creates a terminal that prints to out and reads keystrokes from in until it is closed. When in is the
+// console, put it in raw mode first for characters to arrive as they are typed rather than a line at a time
+func NewTerminal(out io.Writer, in io.Reader) *TerminalDevice {
+	t := &TerminalDevice{
+		output: out,
+		input:  make(chan rune, terminalInputBuffer),
+		done:   make(chan struct{}),
+	}
+	go t.readInput(in)
+	return t
+}
+
+// NewBufferedTerminal creates a terminal that prints into the returned buffer and takes input as its keystrokes,
+// so programs can be run without a console
+func NewBufferedTerminal(input string) (*TerminalDevice, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return NewTerminal(out, strings.NewReader(input)), out
 }
@@ -0,0 +1,173 @@
+package machine
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+// waitForInput waits for the terminal's input goroutine to buffer count characters
+func waitForInput(t *testing.T, term *TerminalDevice, count int) bool {
+	return assert.Eventually(t, func() bool {
+		return len(term.input) == count
+	}, time.Second, time.Millisecond)
+}
+
+func TestTerminalDevice_Write(t *testing.T) {
+	tests := []struct {
+		name     string
+		writes   [][2]uint32
+		expected string
+		x        uint32
+		y        uint32
+	}{
+		{
+			name:     "characters advance the cursor",
+			writes:   [][2]uint32{{TERMINAL, 'h'}, {TERMINAL, 'i'}},
+			expected: "hi",
+			x:        2,
+		},
+		{
+			name:     "numbers advance the cursor",
+			writes:   [][2]uint32{{TERMINAL_INT, 120}},
+			expected: "120",
+			x:        3,
+		},
+		{
+			name:     "newline moves to the next row",
+			writes:   [][2]uint32{{TERMINAL, 'a'}, {TERMINAL, '\n'}, {TERMINAL, 'b'}},
+			expected: "a\nb",
+			x:        1,
+			y:        1,
+		},
+		{
+			name:     "move cursor",
+			writes:   [][2]uint32{{TERMINAL_X, 4}, {TERMINAL_Y, 2}},
+			expected: "\x1b[1;5H\x1b[3;5H",
+			x:        4,
+			y:        2,
+		},
+		{
+			name:     "clear screen homes the cursor",
+			writes:   [][2]uint32{{TERMINAL_X, 4}, {TERMINAL_CONTROL, TERMINAL_CLEAR_SCREEN}},
+			expected: "\x1b[1;5H\x1b[2J\x1b[H",
+		},
+		{
+			name:     "clear line",
+			writes:   [][2]uint32{{TERMINAL, 'a'}, {TERMINAL_CONTROL, TERMINAL_CLEAR_LINE}},
+			expected: "a\x1b[2K\r",
+		},
+		{
+			name:     "cursor visibility",
+			writes:   [][2]uint32{{TERMINAL_CONTROL, TERMINAL_HIDE_CURSOR}, {TERMINAL_CONTROL, TERMINAL_SHOW_CURSOR}},
+			expected: "\x1b[?25l\x1b[?25h",
+		},
+		{
+			name:     "colours",
+			writes:   [][2]uint32{{TERMINAL_COLOUR, 0x41}, {TERMINAL_COLOUR, 0x0F}, {TERMINAL_CONTROL, TERMINAL_RESET_COLOUR}},
+			expected: "\x1b[31;44m\x1b[97;40m\x1b[0m",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			term, out := NewBufferedTerminal("")
+			for _, w := range tt.writes {
+				assert.NoError(t, term.Write(w[0], w[1]))
+			}
+			assert.Equal(t, tt.expected, out.String())
+			x, _ := term.Read(TERMINAL_X)
+			y, _ := term.Read(TERMINAL_Y)
+			assert.Equal(t, tt.x, x)
+			assert.Equal(t, tt.y, y)
+		})
+	}
+	t.Run("output error", func(t *testing.T) {
+		reader, writer := io.Pipe()
+		writer.Close()
+		term := NewTerminal(writer, reader)
+		assert.Error(t, term.Write(TERMINAL, 'a'))
+	})
+	t.Run("unknown command", func(t *testing.T) {
+		term, _ := NewBufferedTerminal("")
+		assert.Error(t, term.Write(TERMINAL_CONTROL, 0xFF))
+	})
+}
+
+func TestTerminalDevice_Read(t *testing.T) {
+	t.Run("no input", func(t *testing.T) {
+		term, _ := NewBufferedTerminal("")
+		status, err := term.Read(TERMINAL_STATUS)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0), status)
+		ch, err := term.Read(TERMINAL)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(0), ch)
+	})
+	t.Run("scripted keystrokes", func(t *testing.T) {
+		term, _ := NewBufferedTerminal("hé")
+		if !waitForInput(t, term, 2) {
+			return
+		}
+		status, err := term.Read(TERMINAL_STATUS)
+		assert.NoError(t, err)
+		assert.Equal(t, TERMINAL_INPUT_READY, status)
+
+		ch, _ := term.Read(TERMINAL)
+		assert.Equal(t, uint32('h'), ch)
+		ch, _ = term.Read(TERMINAL)
+		assert.Equal(t, uint32('é'), ch)
+
+		status, _ = term.Read(TERMINAL_STATUS)
+		assert.Equal(t, uint32(0), status)
+		ch, _ = term.Read(TERMINAL)
+		assert.Equal(t, uint32(0), ch)
+	})
+	t.Run("input raises interrupt", func(t *testing.T) {
+		ic := NewInterruptController()
+		reader, writer := io.Pipe()
+		term := NewTerminal(io.Discard, reader)
+		term.AttachInterruptController(ic)
+		go writer.Write([]byte("a"))
+		if !waitForInput(t, term, 1) {
+			return
+		}
+		assert.Eventually(t, func() bool {
+			pending, _ := ic.Read(INTERRUPT_PENDING)
+			return pending == uint32(1)<<IRQ_TERMINAL
+		}, time.Second, time.Millisecond)
+	})
+	t.Run("closed terminal stops reading", func(t *testing.T) {
+		reader, writer := io.Pipe()
+		term := NewTerminal(io.Discard, reader)
+		assert.NoError(t, term.Close())
+		assert.NoError(t, term.Close())
+		_, err := writer.Write([]byte("a"))
+		assert.NoError(t, err)
+		assert.Never(t, func() bool {
+			status, _ := term.Read(TERMINAL_STATUS)
+			return status == TERMINAL_INPUT_READY
+		}, 50*time.Millisecond, time.Millisecond)
+	})
+	t.Run("program echoes input", func(t *testing.T) {
+		registers := NewRegisterBank()
+		term, _ := NewBufferedTerminal("x")
+		bus := newBus(t, NewMemory(), term)
+		cpu := NewCPU(registers, bus)
+		if !waitForInput(t, term, 1) {
+			return
+		}
+
+		// Wait for input to be ready, then read it into R1
+		bus.Write(0x100, 0x01F0FFE5)
+		bus.Write(0x101, 0x11F00001)
+		bus.Write(0x102, 0x0CF00104)
+		bus.Write(0x103, 0x0CF00100)
+		bus.Write(0x104, 0x01F1FFE1)
+		for i := 0; i < 4; i++ {
+			err := cpu.Tick()
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, uint32('x'), registers.registerMap[R1].Value)
+	})
+}
@@ -0,0 +1,109 @@
+package machine
+
+import "sync"
+
+const (
+	TIMER_COUNTER = uint32(0xFFF4) + iota
+	TIMER_RELOAD
+	TIMER_CONTROL
+	TIMER_STATUS
+)
+
+// Bits in TIMER_CONTROL
+const (
+	// TIMER_ENABLE starts the counter
+	TIMER_ENABLE uint32 = 1 << iota
+	// TIMER_REPEAT reloads the counter when it expires instead of stopping
+	TIMER_REPEAT
+	// TIMER_INTERRUPT raises IRQ_TIMER when the counter expires
+	TIMER_INTERRUPT
+)
+
+// TIMER_EXPIRED is set in TIMER_STATUS when the counter reaches zero
+const TIMER_EXPIRED uint32 = 1
+
+// TimerDevice is a bus device that counts down once per CPU tick, so programs see the same timings on every run
+type TimerDevice struct {
+	lock       sync.Mutex
+	counter    uint32
+	reload     uint32
+	control    uint32
+	status     uint32
+	interrupts *InterruptController
+}
+
+func (t *TimerDevice) MemoryRange() *MemoryRange {
+	// Addresses:
+	// * 0xFFF4 - Ticks left before the timer expires
+	// * 0xFFF5 - Value loaded into the counter when it is enabled at zero or repeats
+	// * 0xFFF6 - Control bits
+	// * 0xFFF7 - Status bits, writing a 1 to a bit clears it
+	return &MemoryRange{
+		Start: 0xFFF4,
+		End:   0xFFF7,
+	}
+}
+
+func (t *TimerDevice) Read(address uint32) (uint32, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	switch address {
+	case TIMER_COUNTER:
+		return t.counter, nil
+	case TIMER_RELOAD:
+		return t.reload, nil
+	case TIMER_CONTROL:
+		return t.control, nil
+	case TIMER_STATUS:
+		return t.status, nil
+	}
+	return 0, nil
+}
+
+func (t *TimerDevice) Write(address, value uint32) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	switch address {
+	case TIMER_COUNTER:
+		t.counter = value
+	case TIMER_RELOAD:
+		t.reload = value
+	case TIMER_CONTROL:
+		if value&TIMER_ENABLE > 0 && t.counter == 0 {
+			t.counter = t.reload
+		}
+		t.control = value
+	case TIMER_STATUS:
+		t.status = t.status &^ value
+	}
+	return nil
+}
+
+// Tick counts the timer down by one
+func (t *TimerDevice) Tick() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.control&TIMER_ENABLE == 0 || t.counter == 0 {
+		return
+	}
+	t.counter--
+	if t.counter > 0 {
+		return
+	}
+	t.status = t.status | TIMER_EXPIRED
+	if t.control&TIMER_INTERRUPT > 0 && t.interrupts != nil {
+		t.interrupts.Raise(IRQ_TIMER)
+	}
+	if t.control&TIMER_REPEAT > 0 {
+		t.counter = t.reload
+	} else {
+		t.control = t.control &^ TIMER_ENABLE
+	}
+}
+
+// NewTimer creates a stopped timer. interrupts may be nil if the timer should never raise IRQ_TIMER
+func NewTimer(interrupts *InterruptController) *TimerDevice {
+	return &TimerDevice{
+		interrupts: interrupts,
+	}
+}
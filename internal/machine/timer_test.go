@@ -0,0 +1,105 @@
+package machine
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTimerDevice(t *testing.T) {
+	t.Run("one shot", func(t *testing.T) {
+		timer := NewTimer(nil)
+		timer.Write(TIMER_COUNTER, 3)
+		timer.Write(TIMER_CONTROL, TIMER_ENABLE)
+
+		timer.Tick()
+		timer.Tick()
+		counter, _ := timer.Read(TIMER_COUNTER)
+		assert.Equal(t, uint32(1), counter)
+		status, _ := timer.Read(TIMER_STATUS)
+		assert.Equal(t, uint32(0), status)
+
+		timer.Tick()
+		status, _ = timer.Read(TIMER_STATUS)
+		assert.Equal(t, TIMER_EXPIRED, status)
+		control, _ := timer.Read(TIMER_CONTROL)
+		assert.Equal(t, uint32(0), control)
+
+		timer.Tick()
+		counter, _ = timer.Read(TIMER_COUNTER)
+		assert.Equal(t, uint32(0), counter)
+	})
+	t.Run("disabled timer does not count", func(t *testing.T) {
+		timer := NewTimer(nil)
+		timer.Write(TIMER_COUNTER, 3)
+
+		timer.Tick()
+		counter, _ := timer.Read(TIMER_COUNTER)
+		assert.Equal(t, uint32(3), counter)
+	})
+	t.Run("enable loads reload value", func(t *testing.T) {
+		timer := NewTimer(nil)
+		timer.Write(TIMER_RELOAD, 5)
+		timer.Write(TIMER_CONTROL, TIMER_ENABLE)
+
+		counter, _ := timer.Read(TIMER_COUNTER)
+		assert.Equal(t, uint32(5), counter)
+	})
+	t.Run("repeat reloads counter", func(t *testing.T) {
+		timer := NewTimer(nil)
+		timer.Write(TIMER_RELOAD, 2)
+		timer.Write(TIMER_CONTROL, TIMER_ENABLE|TIMER_REPEAT)
+
+		timer.Tick()
+		timer.Tick()
+		counter, _ := timer.Read(TIMER_COUNTER)
+		assert.Equal(t, uint32(2), counter)
+		status, _ := timer.Read(TIMER_STATUS)
+		assert.Equal(t, TIMER_EXPIRED, status)
+		control, _ := timer.Read(TIMER_CONTROL)
+		assert.Equal(t, TIMER_ENABLE|TIMER_REPEAT, control)
+	})
+	t.Run("writing status clears expired", func(t *testing.T) {
+		timer := NewTimer(nil)
+		timer.Write(TIMER_COUNTER, 1)
+		timer.Write(TIMER_CONTROL, TIMER_ENABLE)
+		timer.Tick()
+
+		timer.Write(TIMER_STATUS, TIMER_EXPIRED)
+		status, _ := timer.Read(TIMER_STATUS)
+		assert.Equal(t, uint32(0), status)
+	})
+	t.Run("expiry raises interrupt", func(t *testing.T) {
+		ic := NewInterruptController()
+		timer := NewTimer(ic)
+		timer.Write(TIMER_COUNTER, 1)
+		timer.Write(TIMER_CONTROL, TIMER_ENABLE|TIMER_INTERRUPT)
+
+		timer.Tick()
+		assert.Equal(t, uint32(1)<<IRQ_TIMER, ic.pending)
+	})
+	t.Run("counts cpu ticks", func(t *testing.T) {
+		registers := NewRegisterBank()
+		ic := NewInterruptController()
+		timer := NewTimer(ic)
+		bus := newBus(t, NewMemory(), ic, timer)
+		cpu := NewCPU(registers, bus)
+		cpu.AttachInterruptController(ic)
+
+		// Busy loop until the timer handler at 0x200 runs
+		registers.registerMap[SR].Value = STATUS_INTERRUPT_ENABLE
+		bus.Write(IRQ_TIMER, 0x200)
+		bus.Write(TIMER_COUNTER, 3)
+		bus.Write(TIMER_CONTROL, TIMER_ENABLE|TIMER_INTERRUPT)
+		bus.Write(0x100, 0x0CF00100)
+		bus.Write(0x200, 0x00000000)
+
+		for i := 0; i < 3; i++ {
+			err := cpu.Tick()
+			assert.NoError(t, err)
+			assert.Equal(t, uint32(0x100), registers.registerMap[PC].Value)
+		}
+		err := cpu.Tick()
+		assert.NoError(t, err)
+		assert.Equal(t, STATUS_HALT, registers.registerMap[SR].Value&STATUS_HALT)
+	})
+}
@@ -0,0 +1,248 @@
+package machine
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"sync"
+)
+
+// uartFIFOSize is the number of bytes each of the UART's FIFOs can hold
+const uartFIFOSize = 64
+
+const (
+	UART_DATA = uint32(0xFFE8) + iota
+	UART_STATUS
+	UART_CONTROL
+)
+
+// Bits in UART_STATUS
+const (
+	// UART_RX_READY is set while there is a byte waiting to be read
+	UART_RX_READY uint32 = 1 << iota
+	// UART_TX_FULL is set while the transmit FIFO is full. Bytes written now are dropped
+	UART_TX_FULL
+	// UART_OVERRUN is set when a byte was dropped because a FIFO was full. Write a 1 to clear it
+	UART_OVERRUN
+	// UART_CONNECTED is set while something is connected to the other end
+	UART_CONNECTED
+)
+
+// UART_RX_INTERRUPT in UART_CONTROL raises IRQ_UART whenever a byte arrives
+const UART_RX_INTERRUPT uint32 = 1
+
+// UARTDevice is a bus device that passes bytes to and from a host connection, such as a socket or a PTY
+type UARTDevice struct {
+	lock       sync.Mutex
+	rx         chan byte
+	tx         chan byte
+	control    uint32
+	overrun    bool
+	connected  bool
+	interrupts *InterruptController
+	// Closed along with the device
+	closers []io.Closer
+}
+
+func (u *UARTDevice) MemoryRange() *MemoryRange {
+	// Addresses:
+	// * 0xFFE8 - Write a byte to send it or read the next byte received
+	// * 0xFFE9 - Status bits
+	// * 0xFFEA - Control bits
+	return &MemoryRange{
+		Start: 0xFFE8,
+		End:   0xFFEA,
+	}
+}
+
+func (u *UARTDevice) Read(address uint32) (uint32, error) {
+	switch address {
+	case UART_DATA:
+		select {
+		case b := <-u.rx:
+			return uint32(b), nil
+		default:
+			return 0, nil
+		}
+	case UART_STATUS:
+		u.lock.Lock()
+		defer u.lock.Unlock()
+		status := uint32(0)
+		if len(u.rx) > 0 {
+			status = status | UART_RX_READY
+		}
+		if len(u.tx) == cap(u.tx) {
+			status = status | UART_TX_FULL
+		}
+		if u.overrun {
+			status = status | UART_OVERRUN
+		}
+		if u.connected {
+			status = status | UART_CONNECTED
+		}
+		return status, nil
+	case UART_CONTROL:
+		u.lock.Lock()
+		defer u.lock.Unlock()
+		return u.control, nil
+	}
+	return 0, nil
+}
+
+func (u *UARTDevice) Write(address, value uint32) error {
+	switch address {
+	case UART_DATA:
+		select {
+		case u.tx <- byte(value):
+		default:
+			u.lock.Lock()
+			u.overrun = true
+			u.lock.Unlock()
+		}
+	case UART_STATUS:
+		if value&UART_OVERRUN > 0 {
+			u.lock.Lock()
+			u.overrun = false
+			u.lock.Unlock()
+		}
+	case UART_CONTROL:
+		u.lock.Lock()
+		u.control = value
+		u.lock.Unlock()
+	}
+	return nil
+}
+
+// Close disconnects the UART from the host
+func (u *UARTDevice) Close() error {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	var err error
+	for _, c := range u.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	u.closers = nil
+	return err
+}
+
+func (u *UARTDevice) closeWith(c io.Closer) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.closers = append(u.closers, c)
+}
+
+// stopClosingWith forgets a closer that has already been closed
+func (u *UARTDevice) stopClosingWith(c io.Closer) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	for i, closer := range u.closers {
+		if closer == c {
+			u.closers = append(u.closers[:i], u.closers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (u *UARTDevice) setConnected(connected bool) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.connected = connected
+}
+
+// serve passes bytes between the FIFOs and conn until conn closes
+func (u *UARTDevice) serve(conn io.ReadWriter) {
+	u.setConnected(true)
+	defer u.setConnected(false)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		u.receive(conn)
+	}()
+	for {
+		select {
+		case b := <-u.tx:
+			if _, err := conn.Write([]byte{b}); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// receive moves bytes from in to the receive FIFO until in runs out
+func (u *UARTDevice) receive(in io.Reader) {
+	reader := bufio.NewReader(in)
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return
+		}
+		select {
+		case u.rx <- b:
+		default:
+			u.lock.Lock()
+			u.overrun = true
+			u.lock.Unlock()
+			continue
+		}
+		u.lock.Lock()
+		if u.control&UART_RX_INTERRUPT > 0 && u.interrupts != nil {
+			u.interrupts.Raise(IRQ_UART)
+		}
+		u.lock.Unlock()
+	}
+}
+
+func newUART(interrupts *InterruptController) *UARTDevice {
+	return &UARTDevice{
+		rx:         make(chan byte, uartFIFOSize),
+		tx:         make(chan byte, uartFIFOSize),
+		interrupts: interrupts,
+	}
+}
+
+// NewUART creates a UART connected to conn. interrupts may be nil if the UART should never raise IRQ_UART
+func NewUART(conn io.ReadWriter, interrupts *InterruptController) *UARTDevice {
+	u := newUART(interrupts)
+	if c, ok := conn.(io.Closer); ok {
+		u.closeWith(c)
+	}
+	go u.serve(conn)
+	return u
+}
+
+// ListenUART creates a UART that waits for connections on a unix socket or TCP address, as accepted by
+// net.Listen. It serves one connection at a time
+func ListenUART(network, address string, interrupts *InterruptController) (*UARTDevice, net.Addr, error) {
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, nil, err
+	}
+	u := newUART(interrupts)
+	u.closeWith(listener)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			u.closeWith(conn)
+			u.serve(conn)
+			u.stopClosingWith(conn)
+			conn.Close()
+		}
+	}()
+	return u, listener.Addr(), nil
+}
+
+// DialUART creates a UART connected to a listening socket, such as another machine's UART
+func DialUART(network, address string, interrupts *InterruptController) (*UARTDevice, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewUART(conn, interrupts), nil
+}
@@ -0,0 +1,62 @@
+package machine
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// OpenPTYUART creates a UART connected to a new pseudo-terminal, returning the path of the terminal for other
+// programs to open
+func OpenPTYUART(interrupts *InterruptController) (*UARTDevice, string, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	unlock := int32(0)
+	err = ioctl(master, syscall.TIOCSPTLCK, unsafe.Pointer(&unlock))
+	if err != nil {
+		master.Close()
+		return nil, "", err
+	}
+	number := uint32(0)
+	err = ioctl(master, syscall.TIOCGPTN, unsafe.Pointer(&number))
+	if err != nil {
+		master.Close()
+		return nil, "", err
+	}
+	path := fmt.Sprintf("/dev/pts/%d", number)
+	// Hold the terminal open ourselves, otherwise reads fail until something else opens it
+	slave, err := os.OpenFile(path, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, "", err
+	}
+	// Pass bytes straight through rather than echoing them or waiting for whole lines
+	termios := syscall.Termios{}
+	err = ioctl(slave, syscall.TCGETS, unsafe.Pointer(&termios))
+	if err == nil {
+		termios.Iflag = termios.Iflag &^ (syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+			syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON)
+		termios.Oflag = termios.Oflag &^ syscall.OPOST
+		termios.Lflag = termios.Lflag &^ (syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN)
+		err = ioctl(slave, syscall.TCSETS, unsafe.Pointer(&termios))
+	}
+	if err != nil {
+		slave.Close()
+		master.Close()
+		return nil, "", err
+	}
+	u := NewUART(master, interrupts)
+	u.closeWith(slave)
+	return u, path, nil
+}
+
+func ioctl(f *os.File, request uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), request, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
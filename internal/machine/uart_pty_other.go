@@ -0,0 +1,10 @@
+//go:build !linux
+
+package machine
+
+import "errors"
+
+// OpenPTYUART creates a UART connected to a new pseudo-terminal. Pseudo-terminals are only supported on Linux
+func OpenPTYUART(interrupts *InterruptController) (*UARTDevice, string, error) {
+	return nil, "", errors.New("pseudo-terminals are not supported on this platform")
+}
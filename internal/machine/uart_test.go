@@ -0,0 +1,171 @@
+package machine
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitForUART waits until all of the status bits in want are set
+func waitForUART(t *testing.T, u *UARTDevice, want uint32) bool {
+	return assert.Eventually(t, func() bool {
+		status, _ := u.Read(UART_STATUS)
+		return status&want == want
+	}, time.Second, time.Millisecond)
+}
+
+func TestUARTDevice(t *testing.T) {
+	t.Run("send and receive", func(t *testing.T) {
+		host, guest := net.Pipe()
+		defer host.Close()
+		uart := NewUART(guest, nil)
+		defer uart.Close()
+
+		go host.Write([]byte("ok"))
+		if !waitForUART(t, uart, UART_RX_READY|UART_CONNECTED) {
+			return
+		}
+		assert.Eventually(t, func() bool { return len(uart.rx) == 2 }, time.Second, time.Millisecond)
+		b, _ := uart.Read(UART_DATA)
+		assert.Equal(t, uint32('o'), b)
+		b, _ = uart.Read(UART_DATA)
+		assert.Equal(t, uint32('k'), b)
+		status, _ := uart.Read(UART_STATUS)
+		assert.Equal(t, UART_CONNECTED, status)
+
+		assert.NoError(t, uart.Write(UART_DATA, 'x'))
+		received := make([]byte, 1)
+		_, err := io.ReadFull(host, received)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("x"), received)
+	})
+	t.Run("transmit fifo full", func(t *testing.T) {
+		uart := newUART(nil)
+		for i := 0; i < uartFIFOSize; i++ {
+			uart.Write(UART_DATA, uint32(i))
+		}
+		status, _ := uart.Read(UART_STATUS)
+		assert.Equal(t, UART_TX_FULL, status)
+		uart.Write(UART_DATA, 0xFF)
+		status, _ = uart.Read(UART_STATUS)
+		assert.Equal(t, UART_TX_FULL|UART_OVERRUN, status)
+		uart.Write(UART_STATUS, UART_OVERRUN)
+		status, _ = uart.Read(UART_STATUS)
+		assert.Equal(t, UART_TX_FULL, status)
+	})
+	t.Run("raises interrupt", func(t *testing.T) {
+		ic := NewInterruptController()
+		host, guest := net.Pipe()
+		defer host.Close()
+		uart := NewUART(guest, ic)
+		defer uart.Close()
+		uart.Write(UART_CONTROL, UART_RX_INTERRUPT)
+		go host.Write([]byte("a"))
+		assert.Eventually(t, func() bool {
+			pending, _ := ic.Read(INTERRUPT_PENDING)
+			return pending == uint32(1)<<IRQ_UART
+		}, time.Second, time.Millisecond)
+	})
+	t.Run("unix socket", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "uart.sock")
+		uart, _, err := ListenUART("unix", path, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer uart.Close()
+		conn, err := net.Dial("unix", path)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("z"))
+		if !waitForUART(t, uart, UART_RX_READY) {
+			return
+		}
+		b, _ := uart.Read(UART_DATA)
+		assert.Equal(t, uint32('z'), b)
+	})
+	t.Run("forgets closed connections", func(t *testing.T) {
+		uart, addr, err := ListenUART("tcp", "127.0.0.1:0", nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer uart.Close()
+		for i := 0; i < 3; i++ {
+			conn, err := net.Dial("tcp", addr.String())
+			if !assert.NoError(t, err) {
+				return
+			}
+			if !waitForUART(t, uart, UART_CONNECTED) {
+				return
+			}
+			conn.Close()
+			assert.Eventually(t, func() bool {
+				status, _ := uart.Read(UART_STATUS)
+				return status&UART_CONNECTED == 0
+			}, time.Second, time.Millisecond)
+		}
+		assert.Eventually(t, func() bool {
+			uart.lock.Lock()
+			defer uart.lock.Unlock()
+			return len(uart.closers) == 1
+		}, time.Second, time.Millisecond)
+	})
+	t.Run("two machines over tcp", func(t *testing.T) {
+		server, addr, err := ListenUART("tcp", "127.0.0.1:0", nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer server.Close()
+		client, err := DialUART("tcp", addr.String(), nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer client.Close()
+		if !waitForUART(t, server, UART_CONNECTED) {
+			return
+		}
+
+		client.Write(UART_DATA, 'p')
+		if !waitForUART(t, server, UART_RX_READY) {
+			return
+		}
+		b, _ := server.Read(UART_DATA)
+		assert.Equal(t, uint32('p'), b)
+
+		server.Write(UART_DATA, 'q')
+		if !waitForUART(t, client, UART_RX_READY) {
+			return
+		}
+		b, _ = client.Read(UART_DATA)
+		assert.Equal(t, uint32('q'), b)
+	})
+	t.Run("pty", func(t *testing.T) {
+		uart, path, err := OpenPTYUART(nil)
+		if err != nil {
+			t.Skipf("no pseudo-terminals available: %v", err)
+		}
+		defer uart.Close()
+		pty, err := os.OpenFile(path, os.O_RDWR, 0)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer pty.Close()
+		pty.Write([]byte("t"))
+		if !waitForUART(t, uart, UART_RX_READY) {
+			return
+		}
+		b, _ := uart.Read(UART_DATA)
+		assert.Equal(t, uint32('t'), b)
+
+		uart.Write(UART_DATA, 'u')
+		received := make([]byte, 1)
+		_, err = io.ReadFull(pty, received)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("u"), received)
+	})
+}